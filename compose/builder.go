@@ -7,8 +7,10 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/launchrctl/launchr"
@@ -21,16 +23,23 @@ const (
 	gitPrefix      = ".git"
 )
 
+// domainRepo is origin of files from platform directory.
+const domainRepo = "domain repo"
+
 var excludedFolders = map[string]struct{}{".compose": {}}
-var excludedFiles = map[string]struct{}{composeFile: {}}
+var excludedFiles = map[string]struct{}{composeFile: {}, lockFile: {}, overrideFile: {}}
 
 type mergeConflictResolve uint8
 type mergeStrategyType uint8
 type mergeStrategyTarget uint8
 type mergeStrategy struct {
-	s     mergeStrategyType
-	t     mergeStrategyTarget
-	paths []string
+	s          mergeStrategyType
+	t          mergeStrategyTarget
+	paths      []string
+	lists      string
+	precedence string
+	dedup      bool
+	header     bool
 }
 
 const (
@@ -39,9 +48,14 @@ const (
 	removeExtraLocalFiles   mergeStrategyType    = 2
 	ignoreExtraPackageFiles mergeStrategyType    = 3
 	filterPackageFiles      mergeStrategyType    = 4
+	mergeYAMLFiles          mergeStrategyType    = 5
+	mergeJSONFiles          mergeStrategyType    = 6
+	appendFiles             mergeStrategyType    = 7
+	patchPackageFiles       mergeStrategyType    = 8
 	noConflict              mergeConflictResolve = iota
 	resolveToLocal          mergeConflictResolve = 1
 	resolveToPackage        mergeConflictResolve = 2
+	resolveMerged           mergeConflictResolve = 3
 	localStrategy           mergeStrategyTarget  = 1
 	packageStrategy         mergeStrategyTarget  = 2
 )
@@ -56,6 +70,14 @@ var (
 	StrategyIgnoreExtraPackage = "ignore-extra-package-files"
 	// StrategyFilterPackage string const
 	StrategyFilterPackage = "filter-package-files"
+	// StrategyMergeYAML string const
+	StrategyMergeYAML = "merge-yaml"
+	// StrategyMergeJSON string const
+	StrategyMergeJSON = "merge-json"
+	// StrategyAppendFiles string const
+	StrategyAppendFiles = "append-files"
+	// StrategyPatchPackage string const
+	StrategyPatchPackage = "patch-package-files"
 )
 
 // return conflict const (0 - no warning, 1 - conflict with local, 2 conflict with package)
@@ -66,6 +88,13 @@ func cleanStrategyPaths(paths []string) []string {
 	var r []string
 
 	for _, p := range paths {
+		if isStrategyPattern(p) {
+			// Patterns match path itself and everything below, separator isn't needed.
+			neg, pattern := splitNegation(p)
+			r = append(r, neg+filepath.ToSlash(filepath.Clean(pattern)))
+			continue
+		}
+
 		path := filepath.Clean(p)
 		if !strings.HasSuffix(path, string(os.PathSeparator)) {
 			path += string(os.PathSeparator)
@@ -84,10 +113,11 @@ func retrieveStrategies(packages []*Package) ([]*mergeStrategy, map[string][]*me
 		var strategies []*mergeStrategy
 		for _, item := range pkg.GetStrategies() {
 			s, t := identifyStrategy(item.Name)
-			if s == undefinedStrategy {
+			if s == undefinedStrategy || s == patchPackageFiles {
+				// Patches are applied after merge, they don't select entries.
 				continue
 			}
-			strategy := &mergeStrategy{s, t, cleanStrategyPaths(item.Paths)}
+			strategy := &mergeStrategy{s, t, cleanStrategyPaths(item.Paths), item.Lists, item.Precedence, item.Dedup, item.Header}
 
 			if t == localStrategy {
 				ls = append(ls, strategy)
@@ -115,6 +145,14 @@ func identifyStrategy(name string) (mergeStrategyType, mergeStrategyTarget) {
 		s = ignoreExtraPackageFiles
 	case StrategyFilterPackage:
 		s = filterPackageFiles
+	case StrategyMergeYAML:
+		s = mergeYAMLFiles
+	case StrategyMergeJSON:
+		s = mergeJSONFiles
+	case StrategyAppendFiles:
+		s = appendFiles
+	case StrategyPatchPackage:
+		s = patchPackageFiles
 	}
 
 	return s, t
@@ -127,19 +165,59 @@ type Builder struct {
 	sourceDir        string
 	skipNotVersioned bool
 	logConflicts     bool
+	incremental      bool
 	packages         []*Package
+	origins          map[string][]*entryOrigin
 }
 
 type fsEntry struct {
 	Prefix   string
 	Path     string
+	Source   string
 	Entry    fs.FileInfo
 	Excluded bool
 	From     string
+	Content  []byte
+	Merges   []*entryMerge
+	Patches  []string
+}
+
+// sourcePath returns file of entry, mounted package entries are composed to other path.
+func (e *fsEntry) sourcePath() string {
+	return filepath.Join(e.Prefix, e.Source)
+}
+
+// entryOrigin is a source which provided path during merge and the reason it was selected or skipped.
+type entryOrigin struct {
+	From     string
+	Source   string
+	Selected bool
+	Merged   bool
+	Reason   string
 }
 
-func createBuilder(platformDir, targetDir, sourceDir string, skipNotVersioned, logConflicts bool, packages []*Package) *Builder {
-	return &Builder{platformDir, targetDir, sourceDir, skipNotVersioned, logConflicts, packages}
+func createBuilder(platformDir, targetDir, sourceDir string, skipNotVersioned, logConflicts, incremental bool, packages []*Package) *Builder {
+	return &Builder{platformDir, targetDir, sourceDir, skipNotVersioned, logConflicts, incremental, packages, nil}
+}
+
+// traceOrigins makes builder record every source of every path.
+func (b *Builder) traceOrigins() {
+	b.origins = make(map[string][]*entryOrigin)
+}
+
+// addOrigin records source of path. Selected source deselects previously selected ones.
+func (b *Builder) addOrigin(path, from, source string, selected bool, reason string) {
+	if b.origins == nil {
+		return
+	}
+
+	if selected {
+		for _, o := range b.origins[path] {
+			o.Selected = false
+		}
+	}
+
+	b.origins[path] = append(b.origins[path], &entryOrigin{From: from, Source: source, Selected: selected, Reason: reason})
 }
 
 func getVersionedMap(gitDir string) (map[string]bool, error) {
@@ -170,11 +248,129 @@ func getVersionedMap(gitDir string) (map[string]bool, error) {
 
 func (b *Builder) build(ctx context.Context) error {
 	launchr.Term().Println("Creating composition...")
-	err := EnsureDirExists(b.targetDir)
+
+	// Resolve entries first, so nothing is written in case of cycle.
+	entriesTree, err := b.collectEntries(ctx)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := createManifest(entriesTree, b.packages)
+	if err != nil {
+		return err
+	}
+
+	manifestPath := filepath.Join(b.platformDir, ManifestFile)
+	var prev, cur map[string]*ManifestEntry
+	var stats incrementalStats
+	if b.incremental {
+		prevManifest := readManifest(manifestPath)
+		if prevManifest == nil {
+			// Build dir can't be compared without manifest, build from scratch.
+			launchr.Term().Printfln("Build manifest is missing, cleaning build dir: %s", BuildDir)
+			if err = os.RemoveAll(b.targetDir); err != nil {
+				return err
+			}
+		} else {
+			prev, cur = prevManifest.entriesByPath(), manifest.entriesByPath()
+		}
+
+		// Interrupted build doesn't match any manifest, remove it until build is done.
+		if err = os.Remove(manifestPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	err = EnsureDirExists(b.targetDir)
 	if err != nil {
 		return err
 	}
 
+	// @todo check rsync
+	for _, treeItem := range entriesTree {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			sourcePath := treeItem.sourcePath()
+			destPath := filepath.Join(b.targetDir, treeItem.Path)
+			if !isInsideDir(b.targetDir, destPath) {
+				return fmt.Errorf("%w: %s of %s", errOutsideBuild, treeItem.Path, treeItem.From)
+			}
+
+			if b.incremental {
+				if prev != nil && isUnchanged(prev[filepath.ToSlash(treeItem.Path)], cur[filepath.ToSlash(treeItem.Path)], destPath) {
+					stats.unchanged++
+					continue
+				}
+
+				if err = prepareDestPath(destPath, treeItem.Entry.Mode()); err != nil {
+					return err
+				}
+
+				stats.written++
+			}
+
+			isSymlink := false
+			permissions := os.FileMode(dirPermissions)
+
+			switch treeItem.Entry.Mode() & os.ModeType {
+			case os.ModeDir:
+				if err := createDir(destPath, treeItem.Entry.Mode()); err != nil {
+					return err
+				}
+			case os.ModeSymlink:
+				if err := lcopy(sourcePath, destPath); err != nil {
+					return err
+				}
+				isSymlink = true
+			default:
+				permissions = treeItem.Entry.Mode()
+				if treeItem.Content != nil {
+					// Merged documents don't exist in any source.
+					if err := os.WriteFile(destPath, treeItem.Content, permissions); err != nil {
+						return err
+					}
+				} else if err := fcopy(sourcePath, destPath); err != nil {
+					return err
+				}
+			}
+
+			if !isSymlink {
+				if err := os.Chmod(destPath, permissions); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if prev != nil {
+		stats.removed, err = removeStalePaths(b.targetDir, prev, cur)
+		if err != nil {
+			return err
+		}
+
+		launchr.Term().Printfln("Build updated: %d written, %d removed, %d unchanged", stats.written, stats.removed, stats.unchanged)
+	}
+
+	return writeManifest(manifestPath, manifest)
+}
+
+// collectEntries merges domain repo and packages entries according to strategies.
+func (b *Builder) collectEntries(ctx context.Context) ([]*fsEntry, error) {
+	graph, err := buildDependenciesGraph(b.packages)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = graph.TopSort(DependencyRoot)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errDependencyCycle, err)
+	}
+
+	// Topsort visits edges in random order, but conflicts must be resolved the same way on every build.
+	items := dependenciesOrder(b.packages)
+
 	versionedMap := make(map[string]bool)
 	checkVersioned := b.skipNotVersioned
 	if checkVersioned {
@@ -185,6 +381,8 @@ func (b *Builder) build(ctx context.Context) error {
 	}
 
 	ls, ps := retrieveStrategies(b.packages)
+	patchPaths := retrievePatchPaths(b.packages)
+	patchFiles := make(map[string][]string)
 	baseFs := os.DirFS(b.platformDir)
 
 	entriesMap := make(map[string]*fsEntry)
@@ -216,6 +414,7 @@ func (b *Builder) build(ctx context.Context) error {
 			for _, localStrategy := range ls {
 				if localStrategy.s == removeExtraLocalFiles {
 					if ensureStrategyPrefixPath(path, localStrategy.paths) {
+						b.addOrigin(path, domainRepo, filepath.Join(b.platformDir, path), false, "excluded by "+StrategyRemoveExtraLocal+" strategy")
 						return nil
 					}
 				}
@@ -224,25 +423,36 @@ func (b *Builder) build(ctx context.Context) error {
 			// Add .git folder into entriesTree whenever CheckVersioned or not
 			if checkVersioned && !strings.HasPrefix(path, gitPrefix) {
 				if _, ok := versionedMap[path]; !ok {
+					b.addOrigin(path, domainRepo, filepath.Join(b.platformDir, path), false, "not versioned in git")
 					return nil
 				}
 			}
 
+			// Patches are applied to package files, they aren't part of the build.
+			if pkgName := patchesOwner(path, patchPaths, items); pkgName != "" {
+				if !d.IsDir() && isPatchFile(path) {
+					patchFiles[pkgName] = append(patchFiles[pkgName], path)
+				}
+
+				b.addOrigin(path, domainRepo, filepath.Join(b.platformDir, path), false, "patch of package "+pkgName+" by "+StrategyPatchPackage+" strategy")
+				return nil
+			}
+
 			finfo, _ := d.Info()
-			entry := &fsEntry{Prefix: b.platformDir, Path: path, Entry: finfo, Excluded: false, From: "domain repo"}
+			entry := &fsEntry{Prefix: b.platformDir, Path: path, Source: path, Entry: finfo, Excluded: false, From: domainRepo}
 			entriesTree = append(entriesTree, entry)
 			entriesMap[path] = entry
+			b.addOrigin(path, domainRepo, filepath.Join(b.platformDir, path), true, "")
 			return nil
 		}
 	})
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	graph := buildDependenciesGraph(b.packages)
-	items, _ := graph.TopSort(DependencyRoot)
-	targetsMap := getTargetsMap(b.packages)
+	dirsMap := getDirsMap(b.sourceDir, b.packages)
+	mounts := getMountsMap(b.packages)
 
 	if b.logConflicts {
 		launchr.Term().Info().Printf("Conflicting files:\n")
@@ -251,94 +461,123 @@ func (b *Builder) build(ctx context.Context) error {
 	for i := 0; i < len(items); i++ {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return nil, ctx.Err()
 		default:
 			pkgName := items[i]
 			if pkgName != DependencyRoot {
-				pkgPath := filepath.Join(b.sourceDir, pkgName, targetsMap[pkgName])
+				mount := mounts[pkgName]
+				if !isInsideDir(b.targetDir, filepath.Join(b.targetDir, mount.target)) {
+					return nil, fmt.Errorf("%w: target %s of package %s", errOutsideBuild, mount.target, pkgName)
+				}
+
+				pkgPath := filepath.Join(dirsMap[pkgName], mount.subpath)
+				if !isInsideDir(dirsMap[pkgName], pkgPath) {
+					return nil, fmt.Errorf("subpath %s is outside of package %s", mount.subpath, pkgName)
+				}
+
+				if !exists(pkgPath) {
+					return nil, fmt.Errorf("subpath %s doesn't exist in package %s", mount.subpath, pkgName)
+				}
+
 				packageFs := os.DirFS(pkgPath)
 				strategies, ok := ps[pkgName]
-				err = fs.WalkDir(packageFs, ".", func(path string, d fs.DirEntry, err error) error {
-					if err != nil {
-						return err
-					}
+				addPackageEntry := func(path, source string, finfo fs.FileInfo) {
+					var conflictReslv mergeConflictResolve
+					applied := undefinedStrategy
+					entry := &fsEntry{Prefix: pkgPath, Path: path, Source: source, Entry: finfo, Excluded: false, From: pkgName}
 
-					// Skip .git folder from packages
-					if strings.HasPrefix(path, gitPrefix) {
-						return nil
+					// Strategies may replace existing entry, remember where it came from.
+					prevFrom := ""
+					if prev, exists := entriesMap[path]; exists {
+						prevFrom = prev.From
 					}
 
-					var conflictReslv mergeConflictResolve
-					finfo, _ := d.Info()
-					entry := &fsEntry{Prefix: pkgPath, Path: path, Entry: finfo, Excluded: false, From: pkgName}
-
 					if !ok {
 						// No strategies for package. Proceed with default merge.
 						entriesTree, conflictReslv = addEntries(entriesTree, entriesMap, entry, path)
 					} else {
-						entriesTree, conflictReslv = addStrategyEntries(strategies, entriesTree, entriesMap, entry, path)
+						entriesTree, conflictReslv, applied = addStrategyEntries(strategies, entriesTree, entriesMap, entry, path)
+					}
+
+					if b.origins != nil {
+						cur := entriesMap[path]
+						selected := cur != nil && cur.From == pkgName && cur.sourcePath() == entry.sourcePath()
+						b.addOrigin(path, pkgName, entry.sourcePath(), selected, originReason(applied, selected, prevFrom))
+						if conflictReslv == resolveMerged {
+							origins := b.origins[path]
+							origins[len(origins)-1].Merged = true
+						}
 					}
 
 					if b.logConflicts && !finfo.IsDir() {
 						logConflictResolve(conflictReslv, path, pkgName, entriesMap[path])
 					}
+				}
+
+				err = fs.WalkDir(packageFs, ".", func(path string, d fs.DirEntry, err error) error {
+					if err != nil {
+						return err
+					}
+
+					// Skip .git folder and git files like .gitignore from packages, unless strategy targets files.
+					if isGitPath(path) || (strings.HasPrefix(path, gitPrefix) && !isStrategyTarget(mountPath(mount.target, path), d.IsDir(), strategies)) {
+						return nil
+					}
+
+					finfo, _ := d.Info()
+					if path == "." {
+						// Parent directories of target are composed like mounted package root.
+						for _, parent := range mountParents(mount.target) {
+							addPackageEntry(parent, path, finfo)
+						}
+					}
 
+					addPackageEntry(mountPath(mount.target, path), path, finfo)
 					return nil
 				})
 
 				if err != nil {
-					return err
+					return nil, err
 				}
 			}
 		}
 	}
+	if err = resolveMerges(entriesTree); err != nil {
+		return nil, err
+	}
 
-	// @todo check rsync
-	for _, treeItem := range entriesTree {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			sourcePath := filepath.Join(treeItem.Prefix, treeItem.Path)
-			destPath := filepath.Join(b.targetDir, treeItem.Path)
-			isSymlink := false
-			permissions := os.FileMode(dirPermissions)
+	// Patches are applied in dependencies order, patches of package are applied in lexical order.
+	var patches []*packagePatch
+	for _, pkgName := range items {
+		for _, path := range patchFiles[pkgName] {
+			patches = append(patches, &packagePatch{pkgName, path})
+		}
+	}
 
-			switch treeItem.Entry.Mode() & os.ModeType {
-			case os.ModeDir:
-				if err := createDir(destPath, treeItem.Entry.Mode()); err != nil {
-					return err
-				}
-			case os.ModeSymlink:
-				if err := lcopy(sourcePath, destPath); err != nil {
-					return err
-				}
-				isSymlink = true
-			default:
-				permissions = treeItem.Entry.Mode()
-				if err := fcopy(sourcePath, destPath); err != nil {
-					return err
-				}
-			}
+	if err = applyPatches(entriesMap, patches, b.platformDir); err != nil {
+		return nil, err
+	}
 
-			if !isSymlink {
-				if err := os.Chmod(destPath, permissions); err != nil {
-					return err
-				}
-			}
+	return entriesTree, nil
+}
+
+// patchesOwner returns package which patches are stored at domain repo path.
+func patchesOwner(path string, patchPaths map[string][]string, items []string) string {
+	for _, pkgName := range items {
+		if paths, ok := patchPaths[pkgName]; ok && ensureStrategyPrefixPath(path, paths) {
+			return pkgName
 		}
 	}
 
-	return nil
+	return ""
 }
-
-func getTargetsMap(packages []*Package) map[string]string {
-	targets := make(map[string]string)
+func getDirsMap(sourceDir string, packages []*Package) map[string]string {
+	dirs := make(map[string]string)
 	for _, p := range packages {
-		targets[p.GetName()] = p.GetTarget()
+		dirs[p.GetName()] = packageDir(sourceDir, p)
 	}
 
-	return targets
+	return dirs
 }
 
 func logConflictResolve(resolveto mergeConflictResolve, path, pkgName string, entry *fsEntry) {
@@ -346,6 +585,11 @@ func logConflictResolve(resolveto mergeConflictResolve, path, pkgName string, en
 		return
 	}
 
+	if resolveto == resolveMerged {
+		launchr.Term().Info().Printfln("[%s] - %s > Merged into %s", pkgName, path, entry.From)
+		return
+	}
+
 	launchr.Term().Info().Printfln("[%s] - %s > Selected from %s", pkgName, path, entry.From)
 }
 
@@ -362,7 +606,33 @@ func addEntries(entriesTree []*fsEntry, entriesMap map[string]*fsEntry, entry *f
 	return entriesTree, conflictResolve
 }
 
-func addStrategyEntries(strategies []*mergeStrategy, entriesTree []*fsEntry, entriesMap map[string]*fsEntry, entry *fsEntry, path string) ([]*fsEntry, mergeConflictResolve) {
+// originReason explains why package entry was selected or skipped by strategy or default merge.
+func originReason(applied mergeStrategyType, selected bool, prevFrom string) string {
+	switch {
+	case selected && applied == overwriteLocalFile && prevFrom != "":
+		return StrategyOverwriteLocal + " strategy replaced " + prevFrom
+	case selected && applied == filterPackageFiles:
+		return "matched " + StrategyFilterPackage + " strategy"
+	case selected:
+		return ""
+	case applied == mergeYAMLFiles && prevFrom != "":
+		return "merged into " + prevFrom + " by " + StrategyMergeYAML + " strategy"
+	case applied == mergeJSONFiles && prevFrom != "":
+		return "merged into " + prevFrom + " by " + StrategyMergeJSON + " strategy"
+	case applied == appendFiles && prevFrom != "":
+		return "appended to " + prevFrom + " by " + StrategyAppendFiles + " strategy"
+	case applied == ignoreExtraPackageFiles:
+		return "ignored by " + StrategyIgnoreExtraPackage + " strategy"
+	case applied == filterPackageFiles && prevFrom == "":
+		return "filtered out by " + StrategyFilterPackage + " strategy"
+	case prevFrom == domainRepo:
+		return "local file wins by default, " + StrategyOverwriteLocal + " strategy replaces it"
+	default:
+		return "already provided by " + prevFrom
+	}
+}
+
+func addStrategyEntries(strategies []*mergeStrategy, entriesTree []*fsEntry, entriesMap map[string]*fsEntry, entry *fsEntry, path string) ([]*fsEntry, mergeConflictResolve, mergeStrategyType) {
 	conflictResolve := noConflict
 
 	// Apply strategies package strategies
@@ -379,8 +649,10 @@ func addStrategyEntries(strategies []*mergeStrategy, entriesTree []*fsEntry, ent
 				entriesMap[path] = entry
 			} else if ensureStrategyPrefixPath(path, ms.paths) {
 				localMapEntry.Prefix = entry.Prefix
+				localMapEntry.Source = entry.Source
 				localMapEntry.Entry = entry.Entry
 				localMapEntry.From = entry.From
+				localMapEntry.Merges = nil
 
 				// Strategy replaces local Paths by package one.
 				conflictResolve = resolveToPackage
@@ -397,27 +669,71 @@ func addStrategyEntries(strategies []*mergeStrategy, entriesTree []*fsEntry, ent
 				continue
 			}
 			// just do nothing and skip
+		case mergeYAMLFiles, mergeJSONFiles, appendFiles:
+			// Skip strategy if filepath does not match strategy Paths
+			if !ensureStrategyPrefixPath(path, ms.paths) {
+				continue
+			}
+
+			existing, ok := entriesMap[path]
+			if !ok || !existing.Entry.Mode().IsRegular() || !entry.Entry.Mode().IsRegular() {
+				// Nothing to merge with, proceed with default merge.
+				entriesTree, conflictResolve = addEntries(entriesTree, entriesMap, entry, path)
+			} else {
+				// Documents are merged after all entries are collected.
+				existing.Merges = append(existing.Merges, &entryMerge{entry, ms})
+				conflictResolve = resolveMerged
+			}
 		}
 
-		return entriesTree, conflictResolve
+		return entriesTree, conflictResolve, ms.s
 	}
 
-	return addEntries(entriesTree, entriesMap, entry, path)
+	entriesTree, conflictResolve = addEntries(entriesTree, entriesMap, entry, path)
+	return entriesTree, conflictResolve, undefinedStrategy
 }
 
+// ensureStrategyPrefixPath checks if path matches strategy paths. Paths are applied in order,
+// the last matching one wins, so negation may exclude paths matched before.
 func ensureStrategyPrefixPath(path string, strategyPaths []string) bool {
+	matched := false
 	for _, sp := range strategyPaths {
-		if strings.HasPrefix(path, sp) {
-			return true
+		if !isStrategyPattern(sp) {
+			// Literal path matches file itself or content of directory.
+			if strings.HasPrefix(path, sp) || path == strings.TrimSuffix(sp, string(os.PathSeparator)) {
+				matched = true
+			}
+
+			continue
+		}
+
+		neg, pattern := splitNegation(sp)
+		if matchStrategyPattern(pattern, path) {
+			matched = neg == ""
 		}
 	}
 
-	return false
+	return matched
 }
 
 func ensureStrategyContainsPath(path string, strategyPaths []string) bool {
 	for _, sp := range strategyPaths {
-		if strings.Contains(sp, path) {
+		if !isStrategyPattern(sp) {
+			if strings.Contains(sp, path) {
+				return true
+			}
+
+			continue
+		}
+
+		neg, pattern := splitNegation(sp)
+		if neg != "" {
+			continue
+		}
+
+		// Directory may contain matching files if it's above or below static part of the pattern.
+		base, _ := doublestar.SplitPattern(pattern)
+		if base == "." || strings.HasPrefix(base+"/", path+"/") || strings.HasPrefix(path+"/", base+"/") {
 			return true
 		}
 	}
@@ -425,7 +741,140 @@ func ensureStrategyContainsPath(path string, strategyPaths []string) bool {
 	return false
 }
 
-func buildDependenciesGraph(packages []*Package) *topsort.Graph {
+// isGitPath checks if path is .git directory or its content, files like .gitignore are not.
+func isGitPath(path string) bool {
+	return path == gitPrefix || strings.HasPrefix(path, gitPrefix+"/")
+}
+
+// isStrategyTarget checks if path matches paths of any strategy. Directory is a target
+// if strategy path or static part of its pattern is inside it.
+func isStrategyTarget(path string, isDir bool, strategies []*mergeStrategy) bool {
+	for _, ms := range strategies {
+		if ensureStrategyPrefixPath(path, ms.paths) {
+			return true
+		}
+
+		if !isDir {
+			continue
+		}
+
+		for _, sp := range ms.paths {
+			neg, pattern := splitNegation(sp)
+			if neg != "" {
+				continue
+			}
+
+			base, _ := doublestar.SplitPattern(filepath.ToSlash(pattern))
+			if strings.HasPrefix(base+"/", path+"/") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isStrategyPattern checks if strategy path is a doublestar glob or negation instead of literal prefix.
+func isStrategyPattern(sp string) bool {
+	return strings.HasPrefix(sp, "!") || strings.ContainsAny(sp, "*?[{")
+}
+
+func splitNegation(sp string) (string, string) {
+	if strings.HasPrefix(sp, "!") {
+		return "!", sp[1:]
+	}
+
+	return "", sp
+}
+
+// matchStrategyPattern matches path against glob or literal path, pattern matching directory matches its content.
+func matchStrategyPattern(pattern, path string) bool {
+	if !strings.ContainsAny(pattern, "*?[{") {
+		return path == pattern || strings.HasPrefix(path, pattern+"/")
+	}
+
+	return doublestar.MatchUnvalidated(pattern, path) || doublestar.MatchUnvalidated(pattern+"/**", path)
+}
+
+// validateStrategies checks strategy paths and options of merge strategies.
+func validateStrategies(pkg *Package) error {
+	for _, item := range pkg.GetStrategies() {
+		for _, sp := range item.Paths {
+			if err := ValidateStrategyPath(sp); err != nil {
+				return fmt.Errorf("package %s: %w", pkg.GetName(), err)
+			}
+		}
+
+		if item.Name == StrategyMergeYAML || item.Name == StrategyMergeJSON {
+			if err := validateMergeOptions(item); err != nil {
+				return fmt.Errorf("package %s: %w", pkg.GetName(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateStrategyPath checks that strategy path is a valid doublestar pattern, optionally negated with '!'.
+func ValidateStrategyPath(sp string) error {
+	_, pattern := splitNegation(sp)
+	if pattern == "" {
+		return fmt.Errorf("empty strategy path %q", sp)
+	}
+
+	if !doublestar.ValidatePattern(pattern) {
+		return fmt.Errorf("invalid strategy path pattern %q", sp)
+	}
+
+	return nil
+}
+
+// dependenciesOrder sorts packages so that dependencies precede dependents, root is the last item.
+// Root packages are visited by name and dependencies in order of declaration. Packages must not have cycles.
+func dependenciesOrder(packages []*Package) []string {
+	pkgMap := make(map[string]*Package, len(packages))
+	dependent := make(map[string]bool)
+	for _, p := range packages {
+		pkgMap[p.GetName()] = p
+		for _, d := range p.Dependencies {
+			dependent[d] = true
+		}
+	}
+
+	var roots []string
+	for name := range pkgMap {
+		if !dependent[name] {
+			roots = append(roots, name)
+		}
+	}
+	sort.Strings(roots)
+
+	var items []string
+	visited := make(map[string]bool)
+	var visit func(name string, deps []string)
+	visit = func(name string, deps []string) {
+		if visited[name] {
+			return
+		}
+
+		visited[name] = true
+		for _, d := range deps {
+			var next []string
+			if p, ok := pkgMap[d]; ok {
+				next = p.Dependencies
+			}
+
+			visit(d, next)
+		}
+
+		items = append(items, name)
+	}
+
+	visit(DependencyRoot, roots)
+	return items
+}
+
+func buildDependenciesGraph(packages []*Package) (*topsort.Graph, error) {
 	graph := topsort.NewGraph()
 	packageNames := make(map[string]bool)
 
@@ -437,7 +886,10 @@ func buildDependenciesGraph(packages []*Package) *topsort.Graph {
 		graph.AddNode(a.GetName())
 		if a.Dependencies != nil {
 			for _, d := range a.Dependencies {
-				_ = graph.AddEdge(a.GetName(), d)
+				if err := graph.AddEdge(a.GetName(), d); err != nil {
+					return nil, err
+				}
+
 				packageNames[d] = false
 			}
 		}
@@ -445,11 +897,13 @@ func buildDependenciesGraph(packages []*Package) *topsort.Graph {
 
 	for n, k := range packageNames {
 		if k {
-			_ = graph.AddEdge(DependencyRoot, n)
+			if err := graph.AddEdge(DependencyRoot, n); err != nil {
+				return nil, err
+			}
 		}
 	}
 
-	return graph
+	return graph, nil
 }
 
 func lcopy(src, dest string) error {
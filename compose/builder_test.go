@@ -0,0 +1,99 @@
+package compose
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureStrategyPrefixPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		path  string
+		want  bool
+	}{
+		{name: "literal file", paths: []string{"roles/main.yml"}, path: "roles/main.yml", want: true},
+		{name: "literal directory content", paths: []string{"roles"}, path: "roles/tasks/main.yml", want: true},
+		{name: "literal directory itself", paths: []string{"roles/"}, path: "roles", want: true},
+		{name: "literal prefix isn't greedy", paths: []string{"roles"}, path: "roles2/main.yml"},
+		{name: "glob of any depth", paths: []string{"**/*.yml"}, path: "roles/tasks/main.yml", want: true},
+		{name: "glob matches top level", paths: []string{"**/*.yml"}, path: "main.yml", want: true},
+		{name: "glob doesn't match other extension", paths: []string{"**/*.yml"}, path: "roles/main.json"},
+		{name: "single star doesn't cross directories", paths: []string{"*.yml"}, path: "roles/main.yml"},
+		{name: "glob matching directory matches its content", paths: []string{"roles/*"}, path: "roles/tasks/main.yml", want: true},
+		{name: "braces", paths: []string{"{roles,vars}/*.yml"}, path: "vars/main.yml", want: true},
+		{name: "negation excludes path matched before", paths: []string{"roles", "!roles/secret.yml"}, path: "roles/secret.yml"},
+		{name: "negation keeps other paths", paths: []string{"roles", "!roles/secret.yml"}, path: "roles/main.yml", want: true},
+		{name: "path matched after negation is included", paths: []string{"!roles/secret.yml", "roles"}, path: "roles/secret.yml", want: true},
+		{name: "negated glob", paths: []string{"**/*.yml", "!vendor/**"}, path: "vendor/lib/main.yml"},
+		{name: "negation excludes directory content", paths: []string{"**", "!vendor"}, path: "vendor/main.yml"},
+		{name: "re-included after negation", paths: []string{"**/*.yml", "!vendor/**", "vendor/keep.yml"}, path: "vendor/keep.yml", want: true},
+		{name: "only negation matches nothing", paths: []string{"!roles"}, path: "vars/main.yml"},
+		{name: "no paths", path: "main.yml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := make([]string, 0, len(tt.paths))
+			for _, p := range tt.paths {
+				paths = append(paths, filepath.FromSlash(p))
+			}
+
+			got := ensureStrategyPrefixPath(filepath.FromSlash(tt.path), cleanStrategyPaths(paths))
+			if got != tt.want {
+				t.Errorf("ensureStrategyPrefixPath(%q, %q) = %v, want %v", tt.path, tt.paths, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureStrategyContainsPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		dir   string
+		want  bool
+	}{
+		{name: "literal path inside directory", paths: []string{"roles/tasks/main.yml"}, dir: "roles", want: true},
+		{name: "literal path outside directory", paths: []string{"roles/tasks/main.yml"}, dir: "vars"},
+		{name: "pattern base inside directory", paths: []string{"roles/tasks/*.yml"}, dir: "roles", want: true},
+		{name: "directory inside pattern base", paths: []string{"roles/**"}, dir: "roles/tasks", want: true},
+		{name: "pattern of any directory", paths: []string{"**/*.yml"}, dir: "vars", want: true},
+		{name: "pattern base outside directory", paths: []string{"roles/**"}, dir: "vars"},
+		{name: "negation doesn't contain paths", paths: []string{"!vars/**"}, dir: "vars"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ensureStrategyContainsPath(tt.dir, cleanStrategyPaths(tt.paths))
+			if got != tt.want {
+				t.Errorf("ensureStrategyContainsPath(%q, %q) = %v, want %v", tt.dir, tt.paths, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateStrategyPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{path: "roles"},
+		{path: "**/*.yml"},
+		{path: "!vendor/**"},
+		{path: "{roles,vars}/*.yml"},
+		{path: "", wantErr: true},
+		{path: "!", wantErr: true},
+		{path: "roles/[a", wantErr: true},
+		{path: "{roles,vars", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			err := ValidateStrategyPath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateStrategyPath(%q) error = %v, want error: %v", tt.path, err, tt.wantErr)
+			}
+		})
+	}
+}
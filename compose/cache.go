@@ -0,0 +1,363 @@
+package compose
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/launchrctl/launchr"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	cacheInfoExt = ".yaml"
+)
+
+// PackageCache is a user-level cache of downloaded packages shared across projects.
+// Every entry is a copy of a package directory keyed by package URL and resolved revision.
+type PackageCache struct {
+	dir string
+}
+
+// CacheEntry stores information about a cached package revision.
+type CacheEntry struct {
+	Type     string    `yaml:"type"`
+	URL      string    `yaml:"url"`
+	Revision string    `yaml:"revision"`
+	Path     string    `yaml:"-"`
+	Size     int64     `yaml:"-"`
+	LastUsed time.Time `yaml:"-"`
+}
+
+// DefaultCacheDir returns user cache directory for packages, e.g. $XDG_CACHE_HOME/launchr/compose.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "launchr", "compose"), nil
+}
+
+// NewPackageCache creates cache in the given directory.
+func NewPackageCache(dir string) *PackageCache {
+	return &PackageCache{dir: dir}
+}
+
+// Dir returns cache directory.
+func (c *PackageCache) Dir() string {
+	return c.dir
+}
+
+func (c *PackageCache) entryPath(url, revision string) string {
+	urlHash := sha256.Sum256([]byte(url))
+	revisionKey := revision
+	if strings.ContainsAny(revision, ":/+=") {
+		// Content digests aren't safe to be used as a file name.
+		revisionHash := sha256.Sum256([]byte(revision))
+		revisionKey = hex.EncodeToString(revisionHash[:])
+	}
+
+	return filepath.Join(c.dir, hex.EncodeToString(urlHash[:8]), revisionKey)
+}
+
+// has checks if revision of package URL is cached.
+func (c *PackageCache) has(url, revision string) bool {
+	if c == nil || revision == "" {
+		return false
+	}
+
+	return exists(c.entryPath(url, revision) + cacheInfoExt)
+}
+
+// restore populates download path with cached revision of the package.
+// It returns false if the cache doesn't have the revision.
+func (c *PackageCache) restore(pkg *Package, revision, downloadPath string) (bool, error) {
+	if !c.has(pkg.GetURL(), revision) {
+		return false, nil
+	}
+
+	entryPath := c.entryPath(pkg.GetURL(), revision)
+
+	launchr.Term().Printfln("Using cached %s package (%s)", pkg.GetName(), revision)
+	err := copyTree(entryPath, downloadPath, true)
+	if err != nil {
+		errRemove := os.RemoveAll(downloadPath)
+		if errRemove != nil {
+			launchr.Log().Debug("error cleaning package folder", "path", downloadPath, "err", errRemove)
+		}
+
+		return false, err
+	}
+
+	// Track last usage for pruning.
+	now := time.Now()
+	err = os.Chtimes(entryPath+cacheInfoExt, now, now)
+
+	return true, err
+}
+
+// store copies resolved revision of the package to the cache if it's not cached yet.
+func (c *PackageCache) store(pkg *Package, downloadPath string) error {
+	if c == nil || pkg.Revision == "" {
+		return nil
+	}
+
+	entryPath := c.entryPath(pkg.GetURL(), pkg.Revision)
+	if exists(entryPath + cacheInfoExt) {
+		return nil
+	}
+
+	if exists(entryPath) {
+		// Entry is complete once it's renamed, but the process may be interrupted before info is written.
+		return writeCacheInfo(entryPath, pkg)
+	}
+
+	err := EnsureDirExists(filepath.Dir(entryPath))
+	if err != nil {
+		return err
+	}
+
+	// Copy to a temporary directory first, so parallel runs never see incomplete entry.
+	tmpPath, err := os.MkdirTemp(filepath.Dir(entryPath), filepath.Base(entryPath)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpPath)
+
+	err = copyTree(downloadPath, tmpPath, false)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpPath, entryPath)
+	if err != nil && !exists(entryPath) {
+		return err
+	}
+
+	// Info is written even if other process has cached the same revision, it may be interrupted before it.
+	return writeCacheInfo(entryPath, pkg)
+}
+
+// writeCacheInfo writes info file of cache entry. It's renamed into place, so entry is never seen with partial info.
+func writeCacheInfo(entryPath string, pkg *Package) error {
+	info, err := yaml.Marshal(&CacheEntry{Type: pkg.GetType(), URL: pkg.GetURL(), Revision: pkg.Revision})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(entryPath), filepath.Base(entryPath)+cacheInfoExt+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = f.Write(info); err != nil {
+		f.Close() //nolint
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Chmod(f.Name(), os.FileMode(composePermissions)); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), entryPath+cacheInfoExt)
+}
+
+// List returns all cached packages revisions sorted by URL.
+func (c *PackageCache) List() ([]*CacheEntry, error) {
+	var entries []*CacheEntry
+	infoFiles, err := filepath.Glob(filepath.Join(c.dir, "*", "*"+cacheInfoExt))
+	if err != nil {
+		return entries, err
+	}
+
+	for _, infoFile := range infoFiles {
+		data, err := os.ReadFile(filepath.Clean(infoFile))
+		if err != nil {
+			return entries, err
+		}
+
+		entry := &CacheEntry{}
+		if err = yaml.Unmarshal(data, entry); err != nil {
+			launchr.Log().Debug("malformed cache entry", "path", infoFile, "err", err)
+			continue
+		}
+
+		stat, err := os.Stat(infoFile)
+		if err != nil {
+			return entries, err
+		}
+
+		entry.Path = strings.TrimSuffix(infoFile, cacheInfoExt)
+		entry.LastUsed = stat.ModTime()
+		entry.Size, err = dirSize(entry.Path)
+		if err != nil {
+			return entries, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].URL == entries[j].URL {
+			return entries[i].LastUsed.After(entries[j].LastUsed)
+		}
+
+		return entries[i].URL < entries[j].URL
+	})
+
+	return entries, nil
+}
+
+// Prune removes cached revisions not used for longer than given duration.
+// It returns removed entries.
+func (c *PackageCache) Prune(olderThan time.Duration) ([]*CacheEntry, error) {
+	var removed []*CacheEntry
+	entries, err := c.List()
+	if err != nil {
+		return removed, err
+	}
+
+	threshold := time.Now().Add(-olderThan)
+	for _, entry := range entries {
+		if entry.LastUsed.After(threshold) {
+			continue
+		}
+
+		// Remove info file first, so the entry is never used half-removed.
+		if err = os.Remove(entry.Path + cacheInfoExt); err != nil {
+			return removed, err
+		}
+
+		if err = os.RemoveAll(entry.Path); err != nil {
+			return removed, err
+		}
+
+		removed = append(removed, entry)
+	}
+
+	return removed, nil
+}
+
+// ListCache prints cached packages revisions or only total size of the cache.
+func ListCache(sizeOnly bool) error {
+	dir, err := DefaultCacheDir()
+	if err != nil {
+		return err
+	}
+
+	entries, err := NewPackageCache(dir).List()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+		if !sizeOnly {
+			launchr.Term().Printfln("%s %s %s (last used %s)", entry.URL, entry.Revision, humanize.Bytes(uint64(entry.Size)), humanize.Time(entry.LastUsed)) //nolint:gosec
+		}
+	}
+
+	launchr.Term().Printfln("%s: %d revisions, %s", dir, len(entries), humanize.Bytes(uint64(total))) //nolint:gosec
+	return nil
+}
+
+// PruneCache removes cached packages revisions not used for longer than given duration.
+func PruneCache(olderThan time.Duration) error {
+	dir, err := DefaultCacheDir()
+	if err != nil {
+		return err
+	}
+
+	removed, err := NewPackageCache(dir).Prune(olderThan)
+	var freed int64
+	for _, entry := range removed {
+		freed += entry.Size
+		launchr.Term().Printfln("Removed %s %s", entry.URL, entry.Revision)
+	}
+
+	launchr.Term().Printfln("Pruned %d revisions, freed %s", len(removed), humanize.Bytes(uint64(freed))) //nolint:gosec
+	return err
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.Type().IsRegular() {
+			finfo, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			size += finfo.Size()
+		}
+
+		return nil
+	})
+
+	return size, err
+}
+
+// copyTree copies directory recursively preserving file modes and symlinks.
+// If link is true, immutable git objects are hard linked instead of being copied.
+func copyTree(src, dst string, link bool) error {
+	gitObjects := filepath.Join(gitPrefix, "objects") + string(os.PathSeparator)
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		destPath := filepath.Join(dst, rel)
+		finfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch finfo.Mode() & os.ModeType {
+		case os.ModeDir:
+			if err = os.MkdirAll(destPath, finfo.Mode().Perm()); err != nil {
+				return err
+			}
+
+			return os.Chmod(destPath, finfo.Mode().Perm())
+		case os.ModeSymlink:
+			return lcopy(path, destPath)
+		default:
+			if link && strings.HasPrefix(rel, gitObjects) {
+				errLink := os.Link(path, destPath)
+				if errLink == nil {
+					return nil
+				}
+
+				launchr.Log().Debug("hard link failed, copying file", "path", path, "err", errLink)
+			}
+
+			if err = fcopy(path, destPath); err != nil {
+				return err
+			}
+
+			return os.Chmod(destPath, finfo.Mode())
+		}
+	})
+}
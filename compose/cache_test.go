@@ -0,0 +1,151 @@
+package compose
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestCacheStoreRepairsInfo(t *testing.T) {
+	c := NewPackageCache(t.TempDir())
+	pkgDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(pkgDir, "main.yml"), []byte("tasks"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	pkg := &Package{Name: "pkg", Source: Source{Type: HTTPType, URL: "https://example.com/pkg.tar.gz"}, Revision: "h1:abc"}
+	if err := c.store(pkg, pkgDir); err != nil {
+		t.Fatal(err)
+	}
+
+	// Interrupted store leaves entry without info.
+	entryPath := c.entryPath(pkg.GetURL(), pkg.Revision)
+	if err := os.Remove(entryPath + cacheInfoExt); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.has(pkg.GetURL(), pkg.Revision) {
+		t.Fatal("entry without info must not be cached")
+	}
+
+	if err := c.store(pkg, pkgDir); err != nil {
+		t.Fatalf("store error: %v", err)
+	}
+
+	restorePath := filepath.Join(t.TempDir(), "pkg")
+	restored, err := c.restore(pkg, pkg.Revision, restorePath)
+	if err != nil || !restored {
+		t.Fatalf("entry is not restored: %v", err)
+	}
+
+	if content, errRead := os.ReadFile(filepath.Join(restorePath, "main.yml")); errRead != nil || string(content) != "tasks" {
+		t.Errorf("unexpected restored content %q: %v", content, errRead)
+	}
+}
+
+// testGitRepo creates repository with a commit on main branch, dev branch and v1.0.0 tag of the commit.
+func testGitRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err = os.WriteFile(filepath.Join(dir, name), []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = w.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hash, err := w.Commit("init", &git.CommitOptions{Author: &object.Signature{Name: "test", When: time.Now()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refs := []*plumbing.Reference{
+		plumbing.NewHashReference(plumbing.NewBranchReferenceName("main"), hash),
+		plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("main")),
+		plumbing.NewHashReference(plumbing.NewBranchReferenceName("dev"), hash),
+		plumbing.NewHashReference(plumbing.NewTagReferenceName("v1.0.0"), hash),
+	}
+
+	for _, ref := range refs {
+		if err = r.Storer.SetReference(ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestCheckoutRef(t *testing.T) {
+	url := "file://" + testGitRepo(t)
+
+	tests := []struct {
+		name string
+		ref  string
+		head string
+	}{
+		{name: "other branch", ref: "main", head: "refs/heads/main"},
+		{name: "same branch", ref: "dev", head: "refs/heads/dev"},
+		{name: "tag", ref: "v1.0.0", head: "HEAD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &gitDownloader{k: &keyringWrapper{}}
+			repoPath := t.TempDir()
+
+			// Cached copy of dev branch.
+			_, err := git.PlainCloneContext(context.Background(), repoPath, false, &git.CloneOptions{
+				URL:           url,
+				ReferenceName: plumbing.NewBranchReferenceName("dev"),
+				SingleBranch:  true,
+				Tags:          git.NoTags,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pkg := &Package{Name: "pkg", Source: Source{Type: GitType, URL: url, Ref: tt.ref}}
+			if err = g.checkoutRef(pkg, repoPath); err != nil {
+				t.Fatalf("checkout error: %v", err)
+			}
+
+			r, err := git.PlainOpen(repoPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			head, err := r.Head()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if head.Name().String() != tt.head {
+				t.Errorf("HEAD = %s, want %s", head.Name(), tt.head)
+			}
+
+			isLatest, err := g.EnsureLatest(pkg, repoPath)
+			if err != nil || !isLatest {
+				t.Errorf("restored package isn't latest: %v", err)
+			}
+		})
+	}
+}
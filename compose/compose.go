@@ -4,9 +4,11 @@ package compose
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sync"
 	"syscall"
 
 	"github.com/launchrctl/keyring"
@@ -23,14 +25,16 @@ const (
 var (
 	errComposeNotExists    = errors.New("plasma-compose.yaml doesn't exist")
 	errComposeBadStructure = errors.New("incorrect mapping for plasma-compose.yaml, ensure structure is correct")
+	errFrozenUpdateLock    = errors.New("frozen mode can't be used together with lock update")
 )
 
 // Composer stores compose definition
 type Composer struct {
-	pwd     string
-	options *ComposerOptions
-	compose *YamlCompose
-	k       keyring.Keyring
+	pwd       string
+	options   *ComposerOptions
+	compose   *YamlCompose
+	overrides map[string]Source
+	k         keyring.Keyring
 }
 
 // ComposerOptions - list of possible composer options
@@ -40,6 +44,17 @@ type ComposerOptions struct {
 	SkipNotVersioned   bool
 	ConflictsVerbosity bool
 	Interactive        bool
+	UpdateLock         bool
+	Frozen             bool
+	Jobs               int
+	NoCache            bool
+	Offline            bool
+	SSHKey             string
+	Overrides          []string
+	ConflictPolicy     string
+	Incremental        bool
+	DryRun             bool
+	Format             string
 }
 
 // CreateComposer instance
@@ -50,21 +65,61 @@ func CreateComposer(pwd string, opts ComposerOptions, k keyring.Keyring) (*Compo
 	}
 
 	for _, dep := range config.Dependencies {
+		if err = validateSourceType(dep.ToPackage(dep.Name)); err != nil {
+			return nil, err
+		}
+
+		if err = validateStrategies(dep.ToPackage(dep.Name)); err != nil {
+			return nil, err
+		}
+
+		if err = validateMount(dep.ToPackage(dep.Name)); err != nil {
+			return nil, err
+		}
+
 		if dep.Source.Tag != "" {
 			launchr.Term().Warning().Printfln("found deprecated field `tag` in `%s` dependency. Use `ref` field for tags or branches.", dep.Name)
 		}
 	}
 
-	return &Composer{pwd, &opts, config, k}, nil
+	overrides, err := LookupOverride(os.DirFS(pwd))
+	if err != nil {
+		return nil, err
+	}
+
+	// Command line overrides take precedence over overrides file.
+	flagOverrides, err := parseOverrides(opts.Overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, src := range flagOverrides {
+		overrides[name] = src
+	}
+
+	return &Composer{pwd, &opts, config, overrides, k}, nil
 }
 
+// keyringWrapper is safe for concurrent use, credentials are requested one at a time.
 type keyringWrapper struct {
 	keyringService keyring.Keyring
 	interactive    bool
 	shouldUpdate   bool
+	sshKey         string
+	mx             sync.Mutex
 }
 
 func (kw *keyringWrapper) getForURL(url string) (keyring.CredentialsItem, error) {
+	return kw.getForItem(keyring.CredentialsItem{URL: url})
+}
+
+// getForItem returns credentials for item URL. Missing credentials are requested from user,
+// prefilled item fields aren't requested.
+func (kw *keyringWrapper) getForItem(item keyring.CredentialsItem) (keyring.CredentialsItem, error) {
+	kw.mx.Lock()
+	defer kw.mx.Unlock()
+
+	url := item.URL
 	ci, errGet := kw.keyringService.GetForURL(url)
 	if errGet != nil {
 		if errors.Is(errGet, keyring.ErrEmptyPass) {
@@ -75,11 +130,10 @@ func (kw *keyringWrapper) getForURL(url string) (keyring.CredentialsItem, error)
 		}
 
 		if !kw.interactive {
-			return ci, errGet
+			return ci, fmt.Errorf("credentials for %s are missing, add them with keyring:login: %w", url, errGet)
 		}
 
-		ci.URL = url
-		newCI, err := kw.fillCredentials(ci)
+		newCI, err := kw.requestCredentials(item)
 		if err != nil {
 			return ci, err
 		}
@@ -97,7 +151,16 @@ func (kw *keyringWrapper) getForURL(url string) (keyring.CredentialsItem, error)
 }
 
 func (kw *keyringWrapper) fillCredentials(ci keyring.CredentialsItem) (keyring.CredentialsItem, error) {
-	if ci.URL != "" {
+	kw.mx.Lock()
+	defer kw.mx.Unlock()
+
+	return kw.requestCredentials(ci)
+}
+
+func (kw *keyringWrapper) requestCredentials(ci keyring.CredentialsItem) (keyring.CredentialsItem, error) {
+	if ci.URL != "" && ci.Username != "" {
+		launchr.Term().Printfln("Please add password of %s for URL - %s", ci.Username, ci.URL)
+	} else if ci.URL != "" {
 		launchr.Term().Printfln("Please add login and password for URL - %s", ci.URL)
 	}
 	err := keyring.RequestCredentialsFromTty(&ci)
@@ -118,8 +181,10 @@ func (c *Composer) RunInstall() error {
 	go func() {
 		<-signalChan
 		launchr.Term().Printfln("\nTermination signal received. Cleaning up...")
-		// cleanup dir
-		_, _, _ = c.prepareInstall(false)
+		// cleanup dir, dry run doesn't touch it.
+		if !c.options.DryRun {
+			_, _, _ = c.prepareInstall(false)
+		}
 
 		cancel()
 	}()
@@ -128,14 +193,25 @@ func (c *Composer) RunInstall() error {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
+		if len(c.overrides) > 0 && c.options.Format != FormatJSON {
+			printOverridesBanner(c.overrides)
+		}
+
+		lock, err := c.getLock()
+		if err != nil {
+			return err
+		}
+
+		if c.options.DryRun {
+			return c.runDryRun(ctx, lock)
+		}
+
 		buildDir, packagesDir, err := c.prepareInstall(c.options.Clean)
 		if err != nil {
 			return err
 		}
 
-		kw := &keyringWrapper{keyringService: c.getKeyring(), shouldUpdate: false, interactive: c.options.Interactive}
-		dm := CreateDownloadManager(kw)
-		packages, err := dm.Download(ctx, c.getCompose(), packagesDir)
+		packages, err := c.downloadPackages(ctx, packagesDir, lock)
 		if err != nil {
 			return err
 		}
@@ -146,26 +222,114 @@ func (c *Composer) RunInstall() error {
 			packagesDir,
 			c.options.SkipNotVersioned,
 			c.options.ConflictsVerbosity,
+			c.options.Incremental,
 			packages,
 		)
 		return builder.build(ctx)
 	}
 }
 
+// RunLock resolves packages and writes plasma-compose.lock without building.
+func (c *Composer) RunLock() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signalChan
+		launchr.Term().Printfln("\nTermination signal received. Cleaning up...")
+		cancel()
+	}()
+
+	// Lock must reflect plasma-compose.yaml, not local development sources.
+	if len(c.overrides) > 0 {
+		launchr.Term().Warning().Printfln("Overrides are ignored by compose:lock")
+		c.overrides = nil
+	}
+
+	lock, err := c.getLock()
+	if err != nil {
+		return err
+	}
+
+	packagesDir := c.getPath(c.options.WorkingDir)
+	if c.options.Clean {
+		launchr.Term().Printfln("Cleaning packages dir: %s", packagesDir)
+		err = os.RemoveAll(packagesDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = c.downloadPackages(ctx, packagesDir, lock)
+	if err != nil {
+		return err
+	}
+
+	launchr.Term().Printfln("Saved %s", lockFile)
+	return nil
+}
+
+func (c *Composer) downloadPackages(ctx context.Context, packagesDir string, lock *YamlLock) ([]*Package, error) {
+	packages, err := c.resolvePackages(ctx, packagesDir, lock)
+	if err != nil {
+		return nil, err
+	}
+
+	warnUnusedOverrides(c.overrides, packages)
+	if len(c.overrides) > 0 {
+		// Overridden sources are local to the developer machine, don't lock them.
+		launchr.Term().Warning().Printfln("Overrides are active, %s is not updated", lockFile)
+		return packages, nil
+	}
+
+	// Frozen lock is verified to be up to date, keep it untouched.
+	// Offline packages may differ from locked ones, don't lock them.
+	if c.options.Frozen || c.options.Offline {
+		return packages, nil
+	}
+
+	return packages, writeLock(c.pwd, createLock(packages))
+}
+
+// resolvePackages downloads packages and resolves their revisions without writing the lock.
+func (c *Composer) resolvePackages(ctx context.Context, packagesDir string, lock *YamlLock) ([]*Package, error) {
+	kw := &keyringWrapper{keyringService: c.getKeyring(), shouldUpdate: false, interactive: c.options.Interactive, sshKey: c.options.SSHKey}
+	dm := CreateDownloadManager(kw, DownloadOptions{
+		Lock:           lock,
+		Frozen:         c.options.Frozen,
+		Jobs:           c.options.Jobs,
+		Cache:          c.getCache(),
+		Offline:        c.options.Offline,
+		BaseDir:        c.pwd,
+		Overrides:      c.overrides,
+		ConflictPolicy: c.options.ConflictPolicy,
+	})
+	return dm.Download(ctx, c.getCompose(), packagesDir)
+}
+
 func (c *Composer) prepareInstall(clean bool) (string, string, error) {
 	buildPath := c.getPath(BuildDir)
 	packagesPath := c.getPath(c.options.WorkingDir)
 
-	launchr.Term().Printfln("Cleaning build dir: %s", BuildDir)
-	err := os.RemoveAll(buildPath)
-	if err != nil {
-		return "", "", err
+	// Incremental build updates build dir according to manifest of the previous build.
+	if !c.options.Incremental {
+		launchr.Term().Printfln("Cleaning build dir: %s", BuildDir)
+		if err := os.RemoveAll(buildPath); err != nil {
+			return "", "", err
+		}
+
+		// Manifest describes build dir, it's written again after build.
+		if err := os.Remove(c.getPath(ManifestFile)); err != nil && !os.IsNotExist(err) {
+			return "", "", err
+		}
 	}
 
 	if clean {
 		launchr.Term().Printfln("Cleaning packages dir: %s", packagesPath)
-		err = os.RemoveAll(packagesPath)
-		if err != nil {
+		if err := os.RemoveAll(packagesPath); err != nil {
 			return "", "", err
 		}
 	}
@@ -186,6 +350,57 @@ func (c *Composer) getCompose() *YamlCompose {
 	return c.compose
 }
 
+// getLock returns existing lock or nil if lock doesn't exist or update is requested.
+// In frozen mode the lock must exist and be up to date with plasma-compose.yaml.
+func (c *Composer) getLock() (*YamlLock, error) {
+	if c.options.Frozen {
+		if c.options.UpdateLock {
+			return nil, errFrozenUpdateLock
+		}
+
+		if len(c.overrides) > 0 {
+			return nil, errFrozenOverride
+		}
+
+		lock, err := LookupLock(os.DirFS(c.pwd))
+		if err != nil {
+			return nil, err
+		}
+
+		return lock, lock.Verify(c.getCompose())
+	}
+
+	if c.options.UpdateLock {
+		return nil, nil
+	}
+
+	lock, err := LookupLock(os.DirFS(c.pwd))
+	if err != nil {
+		if errors.Is(err, errLockNotExists) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+// getCache returns shared packages cache or nil if cache is disabled or unavailable.
+func (c *Composer) getCache() *PackageCache {
+	if c.options.NoCache {
+		return nil
+	}
+
+	dir, err := DefaultCacheDir()
+	if err != nil {
+		launchr.Log().Debug("packages cache is disabled", "err", err)
+		return nil
+	}
+
+	return NewPackageCache(dir)
+}
+
 func (c *Composer) getKeyring() keyring.Keyring {
 	return c.k
 }
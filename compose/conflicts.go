@@ -0,0 +1,263 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/launchrctl/launchr"
+)
+
+const (
+	// ConflictPolicyFail fails compose if the same package is required in different versions.
+	ConflictPolicyFail = "fail"
+	// ConflictPolicyPreferRoot resolves conflicts to version required by root plasma-compose.yaml.
+	ConflictPolicyPreferRoot = "prefer-root"
+	// ConflictPolicyHighestSemver resolves conflicts to the highest semver ref.
+	ConflictPolicyHighestSemver = "highest-semver"
+)
+
+var (
+	errVersionConflict = errors.New("version conflict")
+)
+
+// packageRequest is a package required by consumer, root plasma-compose.yaml or other package.
+// Parent is the version of consumer package which requires package, it's nil for root.
+type packageRequest struct {
+	consumer string
+	parent   *Package
+	pkg      *Package
+}
+
+// sameSource checks if packages are resolved from the same source.
+func sameSource(a, b *Package) bool {
+	return a.GetType() == b.GetType() && a.GetURL() == b.GetURL() && a.GetTarget() == b.GetTarget()
+}
+
+// addRequest records package required by parent package or by root if parent is nil.
+func (t *downloadTasks) addRequest(parent *Package, pkg *Package) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	consumer := DependencyRoot
+	if parent != nil {
+		consumer = parent.GetName()
+	}
+
+	for _, r := range t.requests[pkg.GetName()] {
+		// The same consumer version is visited once per every path to it.
+		if r.consumer == consumer && (parent == nil || sameSource(r.parent, parent)) && sameSource(r.pkg, pkg) {
+			return
+		}
+	}
+
+	t.requests[pkg.GetName()] = append(t.requests[pkg.GetName()], packageRequest{consumer, parent, pkg})
+}
+
+// resolveConflicts selects a single version of every package required in different versions
+// according to policy and returns packages reachable from root without unused versions.
+// Packages are resolved top-down, only requests of selected consumer versions are taken into account.
+func (m DownloadManager) resolveConflicts(packages []*Package) ([]*Package, error) {
+	winners := make(map[string]*Package)
+	var errs []error
+	for _, name := range requestsOrder(m.tasks.requests) {
+		var active []packageRequest
+		for _, r := range m.tasks.requests[name] {
+			if r.parent == nil || (winners[r.consumer] != nil && sameSource(winners[r.consumer], r.parent)) {
+				active = append(active, r)
+			}
+		}
+
+		if len(active) == 0 {
+			// Required only by versions which lost conflicts.
+			continue
+		}
+
+		winner, err := resolveConflict(name, active, m.opts.ConflictPolicy)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		winners[name] = winner
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	var resolved []*Package
+	added := make(map[string]bool)
+	for _, pkg := range packages {
+		name := pkg.GetName()
+		winner, ok := winners[name]
+		if !ok || added[name] || !sameSource(pkg, winner) {
+			continue
+		}
+
+		added[name] = true
+		resolved = append(resolved, pkg)
+	}
+
+	// Version sharing directory with other source isn't downloaded, see downloadOnce.
+	for name, winner := range winners {
+		if !added[name] {
+			return nil, fmt.Errorf("%w of package %s, %s %s is selected, but other source of the same ref is downloaded to its directory", errVersionConflict, name, winner.GetURL(), winner.GetTarget())
+		}
+	}
+
+	return resolved, nil
+}
+
+// requestsOrder returns names of required packages, consumers precede packages they require.
+func requestsOrder(requests map[string][]packageRequest) []string {
+	indegree := make(map[string]int, len(requests))
+	edges := make(map[string][]string)
+	for name, rs := range requests {
+		if _, ok := indegree[name]; !ok {
+			indegree[name] = 0
+		}
+
+		seen := make(map[string]bool)
+		for _, r := range rs {
+			if r.consumer == DependencyRoot || seen[r.consumer] {
+				continue
+			}
+
+			seen[r.consumer] = true
+			edges[r.consumer] = append(edges[r.consumer], name)
+			indegree[name]++
+		}
+	}
+
+	var ready, order []string
+	for name, d := range indegree {
+		if d == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+		for _, dep := range edges[name] {
+			indegree[dep]--
+			if indegree[dep] == 0 {
+				ready = append(ready, dep)
+			}
+		}
+	}
+
+	// Different versions may require each other, such packages are resolved in name order.
+	if len(order) < len(indegree) {
+		var rest []string
+		for name, d := range indegree {
+			if d > 0 {
+				rest = append(rest, name)
+			}
+		}
+
+		sort.Strings(rest)
+		order = append(order, rest...)
+	}
+
+	return order
+}
+
+// resolveConflict returns package version selected by policy.
+func resolveConflict(name string, requests []packageRequest, policy string) (*Package, error) {
+	var versions []*Package
+	for _, r := range requests {
+		found := false
+		for _, v := range versions {
+			if sameSource(v, r.pkg) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			versions = append(versions, r.pkg)
+		}
+	}
+
+	if len(versions) == 1 {
+		return requests[0].pkg, nil
+	}
+
+	var winner *Package
+	var reason string
+	switch policy {
+	case ConflictPolicyPreferRoot:
+		for _, r := range requests {
+			if r.consumer == DependencyRoot {
+				winner = r.pkg
+				break
+			}
+		}
+
+		reason = "package isn't required by " + composeFile
+	case ConflictPolicyHighestSemver:
+		winner, reason = highestSemver(versions)
+	case ConflictPolicyFail, "":
+		reason = "use prefer-root or highest-semver policy or require single version"
+	default:
+		return nil, fmt.Errorf("unknown version conflict policy %q", policy)
+	}
+
+	report := conflictReport(name, requests)
+	if winner == nil {
+		return nil, fmt.Errorf("%w of package %s, %s:\n%s", errVersionConflict, name, reason, report)
+	}
+
+	launchr.Term().Info().Printfln("Version conflict of package %s resolved to %s %s (%s):\n%s", name, winner.GetURL(), winner.GetTarget(), policy, report)
+	return winner, nil
+}
+
+// highestSemver returns version with the highest semver ref. All versions must be from the same URL.
+func highestSemver(versions []*Package) (*Package, string) {
+	var winner *Package
+	var highest *semver.Version
+	for _, v := range versions {
+		if v.GetType() != versions[0].GetType() || v.GetURL() != versions[0].GetURL() {
+			return nil, "versions come from different sources"
+		}
+
+		ver, err := semver.NewVersion(v.GetTarget())
+		if err != nil {
+			return nil, fmt.Sprintf("ref %s isn't a semantic version", v.GetTarget())
+		}
+
+		if winner == nil || ver.GreaterThan(highest) {
+			winner, highest = v, ver
+		}
+	}
+
+	return winner, ""
+}
+
+// conflictReport lists which consumer requested which version of the package.
+func conflictReport(name string, requests []packageRequest) string {
+	lines := make([]string, 0, len(requests))
+	for _, r := range requests {
+		consumer := r.consumer
+		if consumer == DependencyRoot {
+			consumer = composeFile
+		}
+
+		target := r.pkg.GetTarget()
+		if r.pkg.ResolvedRef != "" {
+			target = fmt.Sprintf("%s (%s)", r.pkg.GetRef(), r.pkg.ResolvedRef)
+		}
+
+		lines = append(lines, fmt.Sprintf("  %s requires %s %s %s", consumer, name, r.pkg.GetURL(), target))
+	}
+
+	// Requests are collected in parallel, keep report stable.
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,149 @@
+package compose
+
+import (
+	"errors"
+	"testing"
+)
+
+func testGitPackage(name, url, ref string) *Package {
+	return &Package{Name: name, Source: Source{Type: GitType, URL: url, Ref: ref}}
+}
+
+type testRequest struct {
+	parent *Package
+	pkg    *Package
+}
+
+func TestResolveConflicts(t *testing.T) {
+	commonV1 := testGitPackage("common", "https://example.com/common.git", "v1.0.0")
+	commonV2 := testGitPackage("common", "https://example.com/common.git", "v2.0.0")
+	app := testGitPackage("app", "https://example.com/app.git", "v1.0.0")
+	xV1 := testGitPackage("x", "https://example.com/x.git", "v1.0.0")
+	xV2 := testGitPackage("x", "https://example.com/x.git", "v2.0.0")
+	fork := testGitPackage("common", "https://example.com/fork/common.git", "v2.0.0")
+	yV19 := testGitPackage("y", "https://example.com/y.git", "v1.9.0")
+	yV110 := testGitPackage("y", "https://example.com/y.git", "1.10.0")
+
+	tests := []struct {
+		name     string
+		policy   string
+		requests []testRequest
+		packages []*Package
+		want     []*Package
+		wantErr  error
+	}{
+		{
+			name:   "single version",
+			policy: ConflictPolicyFail,
+			requests: []testRequest{
+				{nil, app},
+				{app, commonV1},
+				{nil, commonV1},
+			},
+			packages: []*Package{commonV1, app, commonV1},
+			want:     []*Package{commonV1, app},
+		},
+		{
+			name:   "fail policy",
+			policy: ConflictPolicyFail,
+			requests: []testRequest{
+				{nil, commonV2},
+				{nil, app},
+				{app, commonV1},
+			},
+			packages: []*Package{commonV2, commonV1, app},
+			wantErr:  errVersionConflict,
+		},
+		{
+			name:   "requests of losing version are dropped",
+			policy: ConflictPolicyPreferRoot,
+			requests: []testRequest{
+				{nil, commonV2},
+				{nil, app},
+				{app, commonV1},
+				{commonV1, xV1},
+				{commonV2, xV2},
+			},
+			packages: []*Package{xV2, commonV2, xV1, commonV1, app},
+			want:     []*Package{xV2, commonV2, app},
+		},
+		{
+			name:   "highest semver",
+			policy: ConflictPolicyHighestSemver,
+			requests: []testRequest{
+				{nil, app},
+				{nil, xV1},
+				{app, commonV1},
+				{app, xV2},
+			},
+			packages: []*Package{xV1, commonV1, xV2, app},
+			want:     []*Package{commonV1, xV2, app},
+		},
+		{
+			name:   "highest semver with and without v prefix",
+			policy: ConflictPolicyHighestSemver,
+			requests: []testRequest{
+				{nil, yV19},
+				{nil, app},
+				{app, yV110},
+			},
+			packages: []*Package{yV19, yV110, app},
+			want:     []*Package{yV110, app},
+		},
+		{
+			name:   "fork of the same ref is resolved by policy",
+			policy: ConflictPolicyPreferRoot,
+			requests: []testRequest{
+				{nil, commonV2},
+				{nil, app},
+				{app, fork},
+			},
+			// Fork shares directory with root version, it isn't downloaded.
+			packages: []*Package{commonV2, app},
+			want:     []*Package{commonV2, app},
+		},
+		{
+			name:   "fork of the same ref fails by default",
+			policy: ConflictPolicyFail,
+			requests: []testRequest{
+				{nil, commonV2},
+				{nil, app},
+				{app, fork},
+			},
+			packages: []*Package{commonV2, app},
+			wantErr:  errVersionConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := CreateDownloadManager(nil, DownloadOptions{ConflictPolicy: tt.policy})
+			for _, r := range tt.requests {
+				m.tasks.addRequest(r.parent, r.pkg)
+			}
+
+			got, err := m.resolveConflicts(tt.packages)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d packages, want %d", len(got), len(tt.want))
+			}
+
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("package %d = %s %s, want %s %s", i, got[i].GetName(), got[i].GetTarget(), tt.want[i].GetName(), tt.want[i].GetTarget())
+				}
+			}
+		})
+	}
+}
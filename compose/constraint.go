@@ -0,0 +1,100 @@
+package compose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/launchrctl/launchr"
+)
+
+// isVersionConstraint checks if ref is a semver range like ^1.2, ~2.0.3 or >=1.4 <2 instead of exact tag or branch.
+// Ranges use characters which are not allowed in git refs or are unusual at the start of them.
+func isVersionConstraint(ref string) bool {
+	if ref == "" {
+		return false
+	}
+
+	return strings.ContainsAny(ref[:1], "^~<>=") || strings.ContainsAny(ref, " *")
+}
+
+// resolveConstraint selects the highest tag matching semver range of git package.
+// Locked packages keep locked tag, in offline mode only already downloaded tags are considered.
+func (m DownloadManager) resolveConstraint(pkg *Package, kw *keyringWrapper, targetDir string) error {
+	ref := pkg.GetRef()
+	if pkg.GetType() != GitType || !isVersionConstraint(ref) {
+		return nil
+	}
+
+	constraint, err := semver.NewConstraint(ref)
+	if err != nil {
+		return fmt.Errorf("invalid version constraint %q of package %s: %w", ref, pkg.GetName(), err)
+	}
+
+	if pkg.ResolvedRef != "" {
+		return nil
+	}
+
+	var tags []string
+	if m.opts.Offline {
+		entries, errRead := os.ReadDir(filepath.Join(targetDir, pkg.GetName()))
+		if errRead != nil && !os.IsNotExist(errRead) {
+			return errRead
+		}
+
+		for _, entry := range entries {
+			tags = append(tags, entry.Name())
+		}
+	} else {
+		g := &gitDownloader{k: kw}
+		launchr.Term().Printfln("Listing tags of %s", pkg.GetURL())
+		tags, err = g.listTags(pkg.GetURL())
+		if err != nil {
+			return err
+		}
+	}
+
+	tag := highestMatchingTag(tags, constraint)
+	if tag == "" {
+		if m.opts.Offline {
+			return errPackageNotLocal
+		}
+
+		return fmt.Errorf("no tag of package %s matches %s", pkg.GetName(), ref)
+	}
+
+	launchr.Term().Printfln("Resolved %s %s to %s", pkg.GetName(), ref, tag)
+	pkg.ResolvedRef = tag
+	return nil
+}
+
+// highestMatchingTag returns the highest semver tag satisfying all constraints or empty string.
+func highestMatchingTag(tags []string, constraints ...*semver.Constraints) string {
+	var highest *semver.Version
+	var tag string
+	for _, t := range tags {
+		v, err := semver.NewVersion(t)
+		if err != nil || !checkConstraints(v, constraints) {
+			continue
+		}
+
+		if highest == nil || v.GreaterThan(highest) {
+			highest = v
+			tag = t
+		}
+	}
+
+	return tag
+}
+
+func checkConstraints(v *semver.Version, constraints []*semver.Constraints) bool {
+	for _, c := range constraints {
+		if !c.Check(v) {
+			return false
+		}
+	}
+
+	return true
+}
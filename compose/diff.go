@@ -0,0 +1,175 @@
+package compose
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/launchrctl/launchr"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// DiffOptions - list of filters of conflicting files
+type DiffOptions struct {
+	Packages []string
+	Paths    []string
+}
+
+// RunDiff prints unified diff between domain repo file and every package version of it
+// for files provided by both domain repo and packages.
+func (c *Composer) RunDiff(opts DiffOptions) error {
+	builder, err := c.traceBuild(context.Background())
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(builder.origins))
+	for path := range builder.origins {
+		if matchesDiffPath(path, opts.Paths) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	found := false
+	for _, path := range paths {
+		local, candidates := conflictingOrigins(builder.origins[path], opts.Packages)
+		if local == nil || len(candidates) == 0 {
+			continue
+		}
+
+		localPath := local.Source
+		info, errStat := os.Lstat(localPath)
+		if errStat != nil {
+			return errStat
+		}
+
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		for _, o := range candidates {
+			out, errDiff := diffFiles(path, localPath, o)
+			if errDiff != nil {
+				return errDiff
+			}
+
+			if out == "" {
+				continue
+			}
+
+			found = true
+			winner := domainRepo
+			if o.Selected {
+				winner = o.From
+			}
+
+			launchr.Term().Info().Printfln("%s: %s shadows %s", path, winner, shadowed(winner, o.From))
+			launchr.Term().Print(out)
+		}
+	}
+
+	if !found {
+		launchr.Term().Success().Println("No package files differ from domain repo files")
+	}
+
+	return nil
+}
+
+// conflictingOrigins returns domain repo origin of path and package origins matching filter.
+// Domain repo files excluded by strategy or not versioned are not part of the build, they don't conflict.
+func conflictingOrigins(origins []*entryOrigin, packages []string) (*entryOrigin, []*entryOrigin) {
+	var local *entryOrigin
+	var candidates []*entryOrigin
+	for _, o := range origins {
+		if o.From == domainRepo {
+			if o.Reason == "" {
+				local = o
+			}
+
+			continue
+		}
+
+		if len(packages) == 0 || slices.Contains(packages, o.From) {
+			candidates = append(candidates, o)
+		}
+	}
+
+	return local, candidates
+}
+
+func shadowed(winner, pkg string) string {
+	if winner == domainRepo {
+		return pkg
+	}
+
+	return domainRepo
+}
+
+func matchesDiffPath(path string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+
+	for _, p := range prefixes {
+		p = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(p)), "./")
+		if path == p || strings.HasPrefix(path, strings.TrimSuffix(p, "/")+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// diffFiles returns unified diff of domain repo file and package file or empty string if they are equal.
+func diffFiles(path, localPath string, o *entryOrigin) (string, error) {
+	pkgPath := o.Source
+	info, err := os.Lstat(pkgPath)
+	if err != nil {
+		return "", err
+	}
+
+	if !info.Mode().IsRegular() {
+		return "", nil
+	}
+
+	a, err := os.ReadFile(filepath.Clean(localPath))
+	if err != nil {
+		return "", err
+	}
+
+	b, err := os.ReadFile(filepath.Clean(pkgPath))
+	if err != nil {
+		return "", err
+	}
+
+	if bytes.Equal(a, b) {
+		return "", nil
+	}
+
+	from := "a/" + path + " (" + domainRepo + ")"
+	to := "b/" + path + " (" + o.From + ")"
+	if bytes.IndexByte(a, 0) != -1 || bytes.IndexByte(b, 0) != -1 {
+		return "Binary files " + from + " and " + to + " differ\n", nil
+	}
+
+	linesA, linesB := splitContentLines(a), splitContentLines(b)
+	for _, lines := range [][]string{linesA, linesB} {
+		// Last line without line ending would be glued to the next line of diff.
+		if n := len(lines); n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+			lines[n-1] += "\n"
+		}
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        linesA,
+		B:        linesB,
+		FromFile: from,
+		ToFile:   to,
+		Context:  3,
+	})
+}
@@ -2,11 +2,26 @@ package compose
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/launchrctl/launchr"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	errPackageNotLocal = errors.New("package is not available locally")
+	errUnknownType     = errors.New("unknown package source type")
+	errDependencyCycle = errors.New("dependency cycle detected")
+	errSharedDir       = errors.New("package directory is taken by other source")
+	errTransitiveLocal = errors.New("local packages can be declared only in root plasma-compose.yaml or overrides")
 )
 
 const (
@@ -14,17 +29,74 @@ const (
 	GitType = "git"
 	// HTTPType is const for http source type download.
 	HTTPType = "http"
+	// LocalType is const for local directory source type, composed in place without download.
+	LocalType = "local"
 )
 
 // Downloader interface
 type Downloader interface {
 	Download(ctx context.Context, pkg *Package, targetDir string) error
 	EnsureLatest(pkg *Package, downloadPath string) (bool, error)
+	Revision(pkg *Package, downloadPath string) (string, error)
+}
+
+// remoteResolver is implemented by downloaders which resolve package ref to revision without download.
+type remoteResolver interface {
+	remoteRevision(pkg *Package) (string, error)
+}
+
+// refCheckout is implemented by downloaders which check out requested ref of package restored from cache.
+type refCheckout interface {
+	checkoutRef(pkg *Package, downloadPath string) error
 }
 
 // DownloadManager struct, provides methods to fetch packages
 type DownloadManager struct {
-	kw *keyringWrapper
+	kw    *keyringWrapper
+	opts  DownloadOptions
+	sem   chan struct{}
+	tasks *downloadTasks
+}
+
+// DownloadOptions - list of possible download manager options
+type DownloadOptions struct {
+	// Lock pins matching packages to the locked revisions.
+	Lock *YamlLock
+	// Frozen requires every package to match the lock.
+	Frozen bool
+	// Jobs limits number of parallel downloads.
+	Jobs int
+	// Cache is a shared cache of packages, nil disables caching.
+	Cache *PackageCache
+	// Offline uses only packages available locally without remote access.
+	Offline bool
+	// BaseDir is a directory relative paths of local packages are resolved from.
+	BaseDir string
+	// Overrides replace sources of packages by name, including transitive ones.
+	Overrides map[string]Source
+	// ConflictPolicy selects a version of package required in different versions.
+	ConflictPolicy string
+}
+
+// downloadTasks tracks packages downloads, so the same package is downloaded only once.
+type downloadTasks struct {
+	mx       sync.Mutex
+	tasks    map[string]*downloadTask
+	missing  []string
+	requests map[string][]packageRequest
+}
+
+// dependencyLink is a package in chain of dependencies with manifest it's declared in.
+type dependencyLink struct {
+	name     string
+	manifest string
+}
+
+type downloadTask struct {
+	done     chan struct{}
+	url      string
+	revision string
+	err      error
 }
 
 func (m DownloadManager) getKeyring() *keyringWrapper {
@@ -32,19 +104,47 @@ func (m DownloadManager) getKeyring() *keyringWrapper {
 }
 
 // CreateDownloadManager instance
-func CreateDownloadManager(keyring *keyringWrapper) DownloadManager {
-	return DownloadManager{kw: keyring}
+func CreateDownloadManager(keyring *keyringWrapper, opts DownloadOptions) DownloadManager {
+	if opts.Jobs < 1 {
+		opts.Jobs = 1
+	}
+
+	return DownloadManager{
+		kw:    keyring,
+		opts:  opts,
+		sem:   make(chan struct{}, opts.Jobs),
+		tasks: &downloadTasks{tasks: make(map[string]*downloadTask), requests: make(map[string][]packageRequest)},
+	}
+}
+
+func getDownloaderForPackage(downloadType string, kw *keyringWrapper) (Downloader, error) {
+	switch downloadType {
+	case GitType:
+		return newGit(kw), nil
+	case HTTPType:
+		return newHTTP(kw), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownType, downloadType)
+	}
 }
 
-func getDownloaderForPackage(downloadType string, kw *keyringWrapper) Downloader {
-	switch {
-	case downloadType == GitType:
-		return newGit(kw)
-	case downloadType == HTTPType:
-		return newHTTP(kw)
+// validateSourceType checks that package source type is supported.
+func validateSourceType(pkg *Package) error {
+	switch pkg.GetType() {
+	case GitType, HTTPType, LocalType:
+		return nil
 	default:
-		return newGit(kw)
+		return fmt.Errorf("%w %q of package %s, supported types are: %s, %s, %s", errUnknownType, pkg.Source.Type, pkg.GetName(), GitType, HTTPType, LocalType)
+	}
+}
+
+// packageDir returns directory of package content, local packages are used in place.
+func packageDir(packagesDir string, pkg *Package) string {
+	if pkg.GetType() == LocalType {
+		return pkg.Path
 	}
+
+	return filepath.Join(packagesDir, pkg.GetName(), pkg.GetTarget())
 }
 
 // Download packages using compose file
@@ -57,11 +157,30 @@ func (m DownloadManager) Download(ctx context.Context, c *YamlCompose, targetDir
 	}
 
 	kw := m.getKeyring()
-	packages, err = m.recursiveDownload(ctx, c, kw, packages, nil, targetDir)
+	packages, err = m.recursiveDownload(ctx, c, kw, nil, nil, composeFile, targetDir)
+	if err != nil {
+		return packages, err
+	}
+
+	if len(m.tasks.missing) > 0 {
+		sort.Strings(m.tasks.missing)
+		return packages, fmt.Errorf("offline mode, packages are missing locally: %s", strings.Join(m.tasks.missing, ", "))
+	}
+
+	packages, err = m.resolveConflicts(packages)
 	if err != nil {
 		return packages, err
 	}
 
+	// Transitive packages are verified after conflicts resolution, only selected versions must be locked.
+	if m.opts.Frozen {
+		for _, pkg := range packages {
+			if err = m.opts.Lock.verifyPackage(pkg); err != nil {
+				return packages, fmt.Errorf("plasma-compose.lock is out of date: %w", err)
+			}
+		}
+	}
+
 	// store keyring credentials
 	if kw.shouldUpdate {
 		err = kw.keyringService.Save()
@@ -70,61 +189,231 @@ func (m DownloadManager) Download(ctx context.Context, c *YamlCompose, targetDir
 	return packages, err
 }
 
-func (m DownloadManager) recursiveDownload(ctx context.Context, yc *YamlCompose, kw *keyringWrapper, packages []*Package, parent *Package, targetDir string) ([]*Package, error) {
+// recursiveDownload downloads dependencies of the same level in parallel.
+// Resulting packages keep declaration order, every package follows its own dependencies.
+// Chain holds ancestors of the dependencies declared in manifest, it's used to detect cycles.
+func (m DownloadManager) recursiveDownload(ctx context.Context, yc *YamlCompose, kw *keyringWrapper, parent *Package, chain []dependencyLink, manifest, targetDir string) ([]*Package, error) {
+	// All dependencies are validated before downloads start, nothing is left running on error.
+	pkgs := make([]*Package, 0, len(yc.Dependencies))
+	links := make([]dependencyLink, 0, len(yc.Dependencies))
 	for _, d := range yc.Dependencies {
-		select {
-		case <-ctx.Done():
-			return packages, ctx.Err()
-		default:
-			// build package from dependency struct
-			// add dependency if parent exists
-			pkg := d.ToPackage(d.Name)
-			if parent != nil {
-				parent.AddDependency(d.Name)
+		link := dependencyLink{name: d.Name, manifest: manifest}
+		if err := checkCycle(chain, link); err != nil {
+			return nil, err
+		}
+
+		// build package from dependency struct
+		// add dependency if parent exists
+		pkg := d.ToPackage(d.Name)
+		if parent != nil {
+			parent.AddDependency(d.Name)
+		}
+
+		_, overridden := m.opts.Overrides[pkg.GetName()]
+		applyOverride(pkg, m.opts.Overrides)
+
+		// Downloaded package must not compose directories of the host.
+		if parent != nil && !overridden && pkg.GetType() == LocalType {
+			return nil, fmt.Errorf("%w: package %s of %s", errTransitiveLocal, pkg.GetName(), parent.GetName())
+		}
+
+		m.tasks.addRequest(parent, pkg)
+		if m.opts.Frozen && parent == nil {
+			if err := m.opts.Lock.verifyPackage(pkg); err != nil {
+				return nil, fmt.Errorf("plasma-compose.lock is out of date: %w", err)
 			}
+		}
+
+		m.opts.Lock.pin(pkg)
 
-			url := pkg.GetURL()
-			if url == "" {
-				return packages, errNoURL
+		if err := validateSourceType(pkg); err != nil {
+			return nil, err
+		}
+
+		if err := validateMount(pkg); err != nil {
+			return nil, err
+		}
+
+		url := pkg.GetURL()
+		if url == "" {
+			return nil, errNoURL
+		}
+
+		if pkg.GetType() == LocalType {
+			pkg.Path = url
+			if !filepath.IsAbs(url) {
+				pkg.Path = filepath.Join(m.opts.BaseDir, url)
 			}
+		}
+
+		pkgs = append(pkgs, pkg)
+		links = append(links, link)
+	}
+
+	subtrees := make([][]*Package, len(pkgs))
+	g, gctx := errgroup.WithContext(ctx)
+	for i, pkg := range pkgs {
+		g.Go(func() error {
+			packages, err := m.downloadTree(gctx, pkg, kw, append(slices.Clone(chain), links[i]), targetDir)
+			subtrees[i] = packages
+			return err
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
 
-			packagePath := filepath.Join(targetDir, pkg.GetName(), pkg.GetTarget())
+	var packages []*Package
+	for _, subtree := range subtrees {
+		packages = append(packages, subtree...)
+	}
+
+	return packages, nil
+}
+
+// downloadTree downloads package and its dependencies.
+func (m DownloadManager) downloadTree(ctx context.Context, pkg *Package, kw *keyringWrapper, chain []dependencyLink, targetDir string) ([]*Package, error) {
+	var packages []*Package
+	err := m.resolveConstraint(pkg, kw, targetDir)
+	if err == nil {
+		err = m.downloadOnce(ctx, pkg, targetDir, kw)
+	}
+
+	if err != nil {
+		if errors.Is(err, errSharedDir) {
+			// Request is kept, version of other source may lose the conflict.
+			return packages, nil
+		}
 
-			err := downloadPackage(ctx, pkg, targetDir, kw)
+		if errors.Is(err, errPackageNotLocal) {
+			// Collect all missing packages to report them at once.
+			m.tasks.mx.Lock()
+			if !slices.Contains(m.tasks.missing, pkg.GetName()) {
+				m.tasks.missing = append(m.tasks.missing, pkg.GetName())
+			}
+			m.tasks.mx.Unlock()
+			return packages, nil
+		}
+
+		return packages, err
+	}
+
+	// If package has plasma-compose.yaml, proceed with it
+	packagePath := packageDir(targetDir, pkg)
+	if _, err = os.Stat(filepath.Join(packagePath, composeFile)); !os.IsNotExist(err) {
+		cfg, err := Lookup(os.DirFS(packagePath))
+		if err == nil {
+			packages, err = m.recursiveDownload(ctx, cfg, kw, pkg, chain, m.manifestPath(packagePath), targetDir)
 			if err != nil {
 				return packages, err
 			}
+		}
+	}
 
-			// If package has plasma-compose.yaml, proceed with it
-			if _, err = os.Stat(filepath.Join(packagePath, composeFile)); !os.IsNotExist(err) {
-				cfg, err := Lookup(os.DirFS(packagePath))
-				if err == nil {
-					packages, err = m.recursiveDownload(ctx, cfg, kw, packages, pkg, targetDir)
-					if err != nil {
-						return packages, err
-					}
-				}
-			}
+	return append(packages, pkg), nil
+}
+
+// checkCycle returns error with cycle path if package is already in chain of its ancestors.
+// Every edge of the cycle is reported with manifest it's declared in.
+func checkCycle(chain []dependencyLink, link dependencyLink) error {
+	idx := slices.IndexFunc(chain, func(l dependencyLink) bool { return l.name == link.name })
+	if idx == -1 {
+		return nil
+	}
 
-			packages = append(packages, pkg)
+	cycle := append(slices.Clone(chain[idx:]), link)
+	names := make([]string, 0, len(cycle))
+	edges := make([]string, 0, len(cycle)-1)
+	for i, l := range cycle {
+		names = append(names, l.name)
+		if i > 0 {
+			edges = append(edges, fmt.Sprintf("%s -> %s (%s)", cycle[i-1].name, l.name, l.manifest))
 		}
 	}
 
-	return packages, nil
+	return fmt.Errorf("%w: %s\n  %s", errDependencyCycle, strings.Join(names, " -> "), strings.Join(edges, "\n  "))
+}
+
+// manifestPath returns path of package plasma-compose.yaml relative to base dir if possible.
+func (m DownloadManager) manifestPath(packagePath string) string {
+	manifest := filepath.Join(packagePath, composeFile)
+	if rel, err := filepath.Rel(m.opts.BaseDir, manifest); err == nil && m.opts.BaseDir != "" {
+		return rel
+	}
+
+	return manifest
 }
 
-func downloadPackage(ctx context.Context, pkg *Package, targetDir string, kw *keyringWrapper) error {
-	downloader := getDownloaderForPackage(pkg.GetType(), kw)
+// downloadOnce downloads package if it's not downloaded yet by other dependency.
+// Number of simultaneous downloads is limited by jobs.
+func (m DownloadManager) downloadOnce(ctx context.Context, pkg *Package, targetDir string, kw *keyringWrapper) error {
+	key := packageDir(targetDir, pkg)
+
+	m.tasks.mx.Lock()
+	task, ok := m.tasks.tasks[key]
+	if !ok {
+		task = &downloadTask{done: make(chan struct{}), url: pkg.GetURL()}
+		m.tasks.tasks[key] = task
+	}
+	m.tasks.mx.Unlock()
+
+	if ok && task.url != pkg.GetURL() {
+		// Both versions would be downloaded to the same directory, conflict is resolved by policy.
+		launchr.Log().Debug("package directory is taken by other source", "package", pkg.GetName(), "url", pkg.GetURL(), "downloaded", task.url)
+		return errSharedDir
+	}
+
+	if ok {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-task.done:
+			pkg.Revision = task.revision
+			return task.err
+		}
+	}
+
+	defer close(task.done)
+
+	select {
+	case <-ctx.Done():
+		task.err = ctx.Err()
+		return task.err
+	case m.sem <- struct{}{}:
+	}
+
+	task.err = m.downloadPackage(ctx, pkg, targetDir, kw)
+	task.revision = pkg.Revision
+	<-m.sem
+
+	return task.err
+}
+
+func (m DownloadManager) downloadPackage(ctx context.Context, pkg *Package, targetDir string, kw *keyringWrapper) error {
+	if pkg.GetType() == LocalType {
+		return useLocalPath(pkg)
+	}
+
+	downloader, err := getDownloaderForPackage(pkg.GetType(), kw)
+	if err != nil {
+		return err
+	}
+
 	packagePath := filepath.Join(targetDir, pkg.GetName())
 	downloadPath := filepath.Join(packagePath, pkg.GetTarget())
 
+	if m.opts.Offline {
+		return m.useLocalPackage(downloader, pkg, downloadPath)
+	}
+
 	isLatest, err := downloader.EnsureLatest(pkg, downloadPath)
 	if err != nil {
 		return err
 	}
 
 	if isLatest {
-		return nil
+		return m.resolveRevision(downloader, pkg, downloadPath)
 	}
 
 	// Ensure old package doesn't exist in case of update.
@@ -133,20 +422,172 @@ func downloadPackage(ctx context.Context, pkg *Package, targetDir string, kw *ke
 		return err
 	}
 
+	revision := pkg.Pin
+	if revision == "" {
+		revision = m.remoteRevision(downloader, pkg)
+	}
+
+	restored, err := m.restoreCached(downloader, pkg, revision, downloadPath)
+	if err != nil {
+		launchr.Term().Warning().Printfln("Couldn't restore %s package from cache, see debug for detailed error.", pkg.GetName())
+		launchr.Log().Debug("cache restore error", "err", err)
+	}
+
+	if restored {
+		return m.resolveRevision(downloader, pkg, downloadPath)
+	}
+
 	// temporary
+	targetPath := downloadPath
 	if dtype := pkg.GetType(); dtype == HTTPType {
-		downloadPath = packagePath
+		targetPath = packagePath
 	}
 
-	err = downloader.Download(ctx, pkg, downloadPath)
+	err = downloader.Download(ctx, pkg, targetPath)
+	if err != nil {
+		errRemove := os.RemoveAll(targetPath)
+		if errRemove != nil {
+			launchr.Log().Debug("error cleaning package folder", "path", targetPath, "err", err)
+		}
+
+		return err
+	}
+
+	return m.resolveRevision(downloader, pkg, downloadPath)
+}
+
+// restoreCached populates download path with cached revision of package, requested ref is checked out in it.
+// Cache entries are shared by refs of the same revision, so entry may keep ref of other package.
+func (m DownloadManager) restoreCached(downloader Downloader, pkg *Package, revision, downloadPath string) (bool, error) {
+	restored, err := m.opts.Cache.restore(pkg, revision, downloadPath)
+	if err != nil || !restored {
+		return restored, err
+	}
+
+	checkout, ok := downloader.(refCheckout)
+	if !ok {
+		return true, nil
+	}
+
+	err = checkout.checkoutRef(pkg, downloadPath)
 	if err != nil {
 		errRemove := os.RemoveAll(downloadPath)
 		if errRemove != nil {
-			launchr.Log().Debug("error cleaning package folder", "path", downloadPath, "err", err)
+			launchr.Log().Debug("error cleaning package folder", "path", downloadPath, "err", errRemove)
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// remoteRevision resolves ref of not locked package to revision, so it can be restored from cache.
+// Empty string is returned if the cache is disabled or the revision can't be resolved without download.
+func (m DownloadManager) remoteRevision(downloader Downloader, pkg *Package) string {
+	resolver, ok := downloader.(remoteResolver)
+	if !ok || m.opts.Cache == nil {
+		return ""
+	}
+
+	revision, err := resolver.remoteRevision(pkg)
+	if err != nil {
+		launchr.Log().Debug("couldn't resolve remote revision", "package", pkg.GetName(), "err", err)
+		return ""
+	}
+
+	return revision
+}
+
+// useLocalPath checks that local package directory exists. Local packages aren't versioned,
+// so they are neither pinned nor cached.
+func useLocalPath(pkg *Package) error {
+	finfo, err := os.Stat(pkg.Path)
+	if err != nil {
+		return fmt.Errorf("local package %s: %w", pkg.GetName(), err)
+	}
+
+	if !finfo.IsDir() {
+		return fmt.Errorf("local package %s: %s is not a directory", pkg.GetName(), pkg.Path)
+	}
+
+	launchr.Term().Printfln("Using local package %s from %s", pkg.GetName(), pkg.Path)
+	return nil
+}
+
+// useLocalPackage resolves package from working dir or cache without remote access.
+// Locked revision is preferred if it's available locally.
+func (m DownloadManager) useLocalPackage(downloader Downloader, pkg *Package, downloadPath string) error {
+	found := exists(downloadPath)
+	if found {
+		emptyDir, err := IsEmptyDir(downloadPath)
+		if err != nil {
+			return err
+		}
+
+		found = !emptyDir
+	}
+
+	if pkg.Pin != "" {
+		if found {
+			// Locked packages are checked without remote access.
+			isLatest, err := downloader.EnsureLatest(pkg, downloadPath)
+			if err != nil {
+				return err
+			}
+
+			if isLatest {
+				return m.resolveRevision(downloader, pkg, downloadPath)
+			}
 		}
+
+		if m.opts.Cache.has(pkg.GetURL(), pkg.Pin) {
+			err := os.RemoveAll(downloadPath)
+			if err != nil {
+				return err
+			}
+
+			restored, err := m.restoreCached(downloader, pkg, pkg.Pin, downloadPath)
+			if err != nil {
+				return err
+			}
+
+			if restored {
+				return m.resolveRevision(downloader, pkg, downloadPath)
+			}
+		}
+
+		if found {
+			launchr.Term().Warning().Printfln("Locked revision of %s package is not available offline, using local one", pkg.GetName())
+			pkg.Pin = ""
+		}
+	}
+
+	if !found {
+		return errPackageNotLocal
+	}
+
+	return m.resolveRevision(downloader, pkg, downloadPath)
+}
+
+// resolveRevision sets resolved revision to package and stores it in cache.
+func (m DownloadManager) resolveRevision(downloader Downloader, pkg *Package, downloadPath string) error {
+	revision, err := downloader.Revision(pkg, downloadPath)
+	if err != nil {
+		return err
+	}
+
+	if pkg.Pin != "" && pkg.Pin != revision {
+		return fmt.Errorf("package %s resolved to %s, but it's locked to %s, use --update-lock to resolve it again", pkg.GetName(), revision, pkg.Pin)
+	}
+
+	pkg.Revision = revision
+	err = m.opts.Cache.store(pkg, downloadPath)
+	if err != nil {
+		launchr.Log().Debug("cache store error", "package", pkg.GetName(), "err", err)
 	}
 
-	return err
+	return nil
 }
 
 // IsEmptyDir check if directory has at least 1 file.
@@ -164,7 +605,7 @@ func IsEmptyDir(name string) (bool, error) {
 
 	// Check if .git exists and nothing else
 	gitPath := filepath.Join(name, ".git")
-	if _, err = os.Stat(gitPath); err == nil {
+	if _, errStat := os.Stat(gitPath); errStat == nil {
 		// .git exists, now check if it's the only entry
 		entries, err := f.Readdirnames(2) // Read at most 2 entries
 		if err != nil {
@@ -0,0 +1,60 @@
+package compose
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckCycle(t *testing.T) {
+	chain := []dependencyLink{
+		{name: "app", manifest: composeFile},
+		{name: "common", manifest: ".compose/packages/app/v1/" + composeFile},
+		{name: "lib", manifest: ".compose/packages/common/v1/" + composeFile},
+	}
+
+	tests := []struct {
+		name    string
+		chain   []dependencyLink
+		link    dependencyLink
+		wantErr string
+	}{
+		{name: "root dependency", link: dependencyLink{name: "app", manifest: composeFile}},
+		{name: "new package", chain: chain, link: dependencyLink{name: "x", manifest: ".compose/packages/lib/v1/" + composeFile}},
+		{
+			name:    "cycle to root package",
+			chain:   chain,
+			link:    dependencyLink{name: "app", manifest: ".compose/packages/lib/v1/" + composeFile},
+			wantErr: "dependency cycle detected: app -> common -> lib -> app\n  app -> common (.compose/packages/app/v1/plasma-compose.yaml)\n  common -> lib (.compose/packages/common/v1/plasma-compose.yaml)\n  lib -> app (.compose/packages/lib/v1/plasma-compose.yaml)",
+		},
+		{
+			name:    "cycle in the middle of chain",
+			chain:   chain,
+			link:    dependencyLink{name: "common", manifest: ".compose/packages/lib/v1/" + composeFile},
+			wantErr: "common -> lib -> common\n",
+		},
+		{
+			name:    "package depends on itself",
+			chain:   chain[:1],
+			link:    dependencyLink{name: "app", manifest: ".compose/packages/app/v1/" + composeFile},
+			wantErr: "app -> app\n  app -> app (.compose/packages/app/v1/plasma-compose.yaml)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCycle(tt.chain, tt.link)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, errDependencyCycle) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package compose
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/launchrctl/launchr"
+)
+
+const (
+	// ChangeCreate is a path which doesn't exist in build yet.
+	ChangeCreate = "create"
+	// ChangeOverwrite is a path of build which content, type or mode changes.
+	ChangeOverwrite = "overwrite"
+	// ChangeRemove is a path of build which isn't composed anymore.
+	ChangeRemove = "remove"
+)
+
+// BuildChange is a path of build changed by compose.
+type BuildChange struct {
+	Path   string `json:"path"`
+	Action string `json:"action"`
+	Source string `json:"source,omitempty"`
+}
+
+// runDryRun resolves packages and merges them without writing build dir and lock, prints changes of build dir.
+func (c *Composer) runDryRun(ctx context.Context, lock *YamlLock) error {
+	if c.options.Format != FormatJSON && c.options.Format != FormatText && c.options.Format != "" {
+		return fmt.Errorf("unknown output format %q", c.options.Format)
+	}
+
+	// Only the changes must be printed in machine-readable format.
+	// Credentials can't be requested in muted terminal, missing ones fail the run.
+	if c.options.Format == FormatJSON {
+		c.options.Interactive = false
+		launchr.Term().DisableOutput()
+	}
+
+	packagesDir := c.getPath(c.options.WorkingDir)
+	packages, err := c.resolvePackages(ctx, packagesDir, lock)
+	launchr.Term().EnableOutput()
+	if err != nil {
+		return err
+	}
+
+	// Conflicts log would break machine-readable output.
+	logConflicts := c.options.ConflictsVerbosity && c.options.Format != FormatJSON
+	builder := createBuilder(c.pwd, c.getPath(BuildDir), packagesDir, c.options.SkipNotVersioned, logConflicts, false, packages)
+	changes, unchanged, err := builder.plan(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.options.Format == FormatJSON {
+		data, errJSON := json.MarshalIndent(changes, "", "  ")
+		if errJSON != nil {
+			return errJSON
+		}
+
+		launchr.Term().Println(string(data))
+		return nil
+	}
+
+	for _, ch := range changes {
+		if ch.Source != "" {
+			launchr.Term().Printfln("%-9s %s (%s)", ch.Action, ch.Path, ch.Source)
+		} else {
+			launchr.Term().Printfln("%-9s %s", ch.Action, ch.Path)
+		}
+	}
+
+	launchr.Term().Info().Printfln("Dry run: %d paths would be changed, %d unchanged, %s is not modified", len(changes), unchanged, BuildDir)
+	return nil
+}
+
+// plan merges entries like build and compares them with build dir. Nothing is written.
+func (b *Builder) plan(ctx context.Context) ([]*BuildChange, int, error) {
+	entriesTree, err := b.collectEntries(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	manifest, err := createManifest(entriesTree, b.packages)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	changes := make([]*BuildChange, 0)
+	unchanged := 0
+	for _, me := range manifest.Files {
+		destPath := filepath.Join(b.targetDir, filepath.FromSlash(me.Path))
+		same, errCmp := matchesBuild(me, destPath)
+		switch {
+		case errCmp != nil && os.IsNotExist(errCmp):
+			changes = append(changes, &BuildChange{Path: me.Path, Action: ChangeCreate, Source: me.Source})
+		case errCmp != nil:
+			return nil, 0, errCmp
+		case same:
+			unchanged++
+		default:
+			changes = append(changes, &BuildChange{Path: me.Path, Action: ChangeOverwrite, Source: me.Source})
+		}
+	}
+
+	if !exists(b.targetDir) {
+		return changes, unchanged, nil
+	}
+
+	composed := manifest.entriesByPath()
+	err = fs.WalkDir(os.DirFS(b.targetDir), ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if _, ok := composed[path]; ok {
+			return nil
+		}
+
+		changes = append(changes, &BuildChange{Path: path, Action: ChangeRemove})
+		if d.IsDir() {
+			// Content is removed together with directory.
+			return fs.SkipDir
+		}
+
+		return nil
+	})
+
+	return changes, unchanged, err
+}
+
+// matchesBuild checks if path of build dir has the same type, mode and content as manifest entry.
+func matchesBuild(me *ManifestEntry, destPath string) (bool, error) {
+	info, err := os.Lstat(destPath)
+	if err != nil {
+		return false, err
+	}
+
+	if manifestType(info.Mode()) != me.Type {
+		return false, nil
+	}
+
+	switch me.Type {
+	case manifestTypeDir:
+		return true, nil
+	case manifestTypeSymlink:
+		target, errLink := os.Readlink(destPath)
+		return target == me.Target, errLink
+	default:
+		if info.Size() != me.Size || fmt.Sprintf("%04o", info.Mode().Perm()) != me.Mode {
+			return false, nil
+		}
+
+		hash, errHash := fileHash(destPath)
+		return hash == me.Hash, errHash
+	}
+}
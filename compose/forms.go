@@ -9,6 +9,7 @@ import (
 
 	"dario.cat/mergo"
 	"github.com/charmbracelet/huh"
+	"github.com/launchrctl/keyring"
 	"github.com/launchrctl/launchr"
 )
 
@@ -19,7 +20,9 @@ type RawStrategies struct {
 }
 
 // AddPackage adds a new package to plasma-compose.
-func AddPackage(doCreate bool, newDependency *Dependency, rawStrategies *RawStrategies, dir string) error {
+// If computeChecksum is set, archive of http package is downloaded to record its checksum,
+// credentials are requested during download only in interactive mode.
+func AddPackage(doCreate, computeChecksum, interactive bool, newDependency *Dependency, rawStrategies *RawStrategies, dir string, k keyring.Keyring) error {
 	config, err := Lookup(os.DirFS(dir))
 	if err != nil {
 		if !errors.Is(err, errComposeNotExists) {
@@ -72,6 +75,17 @@ func AddPackage(doCreate bool, newDependency *Dependency, rawStrategies *RawStra
 	}
 
 	sanitizeDependency(newDependency)
+	if computeChecksum {
+		if newDependency.Source.Type != HTTPType {
+			launchr.Term().Warning().Println("Checksum can be computed only for HTTP source")
+		} else {
+			newDependency.Source.Checksum, err = ComputeChecksum(newDependency, k, interactive)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	config.Dependencies = append(config.Dependencies, *newDependency)
 	launchr.Term().Println("Saving plasma-compose...")
 	sortPackages(config)
@@ -276,6 +290,10 @@ func processStrategiesForm(dependency *Dependency) error {
 							huh.NewOption("Remove Extra Local Files", StrategyRemoveExtraLocal),
 							huh.NewOption("Ignore Extra Package", StrategyIgnoreExtraPackage),
 							huh.NewOption("Filter Package Files", StrategyFilterPackage),
+							huh.NewOption("Merge YAML Files", StrategyMergeYAML),
+							huh.NewOption("Merge JSON Files", StrategyMergeJSON),
+							huh.NewOption("Append Files", StrategyAppendFiles),
+							huh.NewOption("Patch Package Files", StrategyPatchPackage),
 						).
 						Value(&selectedStrategy),
 
@@ -349,6 +367,7 @@ func preparePackageForm(dependency *Dependency, config *YamlCompose, isAdd bool)
 				Options(
 					huh.NewOption("Git", GitType).Selected(true),
 					huh.NewOption("Http", HTTPType),
+					huh.NewOption("Local directory", LocalType),
 				).
 				Value(&dependency.Source.Type),
 
@@ -380,6 +399,47 @@ func preparePackageForm(dependency *Dependency, config *YamlCompose, isAdd bool)
 				Title("- Enter Ref").
 				Value(&dependency.Source.Ref),
 		).WithHideFunc(func() bool { return dependency.Source.Type != GitType }),
+
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("- Select auth mode").
+				Options(
+					huh.NewOption("Basic", HTTPAuthBasic).Selected(true),
+					huh.NewOption("Bearer token", HTTPAuthBearer),
+					huh.NewOption("Custom header", HTTPAuthHeader),
+				).
+				Value(&dependency.Source.Auth),
+		).WithHideFunc(func() bool { return dependency.Source.Type != HTTPType }),
+
+		huh.NewGroup(
+			huh.NewInput().
+				Title("- Enter auth header, e.g. PRIVATE-TOKEN: {{ .Password }}").
+				Value(&dependency.Source.Header).
+				Validate(func(str string) error {
+					_, _, err := parseAuthHeader(str)
+					return err
+				}),
+		).WithHideFunc(func() bool {
+			return dependency.Source.Type != HTTPType || dependency.Source.Auth != HTTPAuthHeader
+		}),
+
+		huh.NewGroup(
+			huh.NewInput().
+				Title("- Enter archive checksum in format sha256:<hex>, leave empty to skip").
+				Value(&dependency.Source.Checksum).
+				Validate(ValidateChecksum),
+		).WithHideFunc(func() bool { return dependency.Source.Type != HTTPType }),
+
+		huh.NewGroup(
+			huh.NewInput().
+				Title("- Enter package directory to compose, leave empty for the whole package").
+				Value(&dependency.Source.Subpath).
+				Validate(ValidateMountPath),
+			huh.NewInput().
+				Title("- Enter build directory to compose package into, leave empty for the build root").
+				Value(&dependency.Source.Target).
+				Validate(ValidateMountPath),
+		),
 	)
 }
 
@@ -414,4 +474,21 @@ func sanitizeDependency(dependency *Dependency) {
 	dependency.Name = strings.TrimSpace(dependency.Name)
 	dependency.Source.URL = strings.TrimSpace(dependency.Source.URL)
 	dependency.Source.Ref = strings.TrimSpace(dependency.Source.Ref)
+	dependency.Source.Header = strings.TrimSpace(dependency.Source.Header)
+	dependency.Source.Checksum = strings.ToLower(strings.TrimSpace(dependency.Source.Checksum))
+	dependency.Source.Subpath = strings.TrimSpace(dependency.Source.Subpath)
+	dependency.Source.Target = strings.TrimSpace(dependency.Source.Target)
+
+	// Basic auth is default, keep plasma-compose clean.
+	if dependency.Source.Auth == HTTPAuthBasic {
+		dependency.Source.Auth = ""
+	}
+
+	if dependency.Source.Auth != HTTPAuthHeader {
+		dependency.Source.Header = ""
+	}
+
+	if dependency.Source.Type != HTTPType {
+		dependency.Source.Checksum = ""
+	}
 }
@@ -6,16 +6,23 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/launchrctl/keyring"
 	"github.com/launchrctl/launchr"
 )
 
+var (
+	errNoSSHKey = errors.New("SSH private key is not found, pass it with --ssh-key")
+)
+
 type gitDownloader struct {
 	k *keyringWrapper
 }
@@ -38,75 +45,17 @@ func (g *gitDownloader) fetchRemotes(r *git.Repository, url string, refSpec []co
 			Force:    true,
 		}
 
-		auths := []authorizationMode{authorisationNone, authorisationKeyring, authorisationManual}
-		for _, authType := range auths {
-			if authType == authorisationNone {
-				err := rem.Fetch(&options)
-				if err != nil {
-					if errors.Is(err, transport.ErrAuthenticationRequired) {
-						continue
-					}
-
-					if !errors.Is(err, git.NoErrAlreadyUpToDate) {
-						return err
-					}
-
-					return nil
-				}
-			}
-
-			if authType == authorisationKeyring {
-				ci, err := g.k.getForURL(url)
-				if err != nil {
-					return err
-				}
-
-				options.Auth = &http.BasicAuth{
-					Username: ci.Username,
-					Password: ci.Password,
-				}
-
-				err = rem.Fetch(&options)
-				if err != nil {
-					if errors.Is(err, transport.ErrAuthorizationFailed) || errors.Is(err, transport.ErrAuthenticationRequired) {
-						if g.k.interactive {
-							launchr.Term().Println("invalid auth, trying manual authorisation")
-							continue
-						}
-					}
-
-					if !errors.Is(err, git.NoErrAlreadyUpToDate) {
-						return err
-					}
-
-					return nil
-				}
+		err := g.withAuth(url, func(auth transport.AuthMethod) error {
+			options.Auth = auth
+			err := rem.Fetch(&options)
+			if errors.Is(err, git.NoErrAlreadyUpToDate) {
+				return nil
 			}
 
-			if authType == authorisationManual {
-				ci := keyring.CredentialsItem{}
-				ci.URL = url
-				ci, err := g.k.fillCredentials(ci)
-				if err != nil {
-					return err
-				}
-
-				options.Auth = &http.BasicAuth{
-					Username: ci.Username,
-					Password: ci.Password,
-				}
-
-				err = rem.Fetch(&options)
-				if err != nil {
-					if !errors.Is(err, git.NoErrAlreadyUpToDate) {
-						return err
-					}
-
-					return nil
-				}
-			}
-
-			break
+			return err
+		})
+		if err != nil {
+			return err
 		}
 	}
 
@@ -140,8 +89,18 @@ func (g *gitDownloader) EnsureLatest(pkg *Package, downloadPath string) (bool, e
 		return false, fmt.Errorf("can't get HEAD of '%s', ensure package is valid", pkg.GetName())
 	}
 
+	if pkg.Pin != "" {
+		// Locked package doesn't need remote check, it's enough to compare local HEAD.
+		isLatest := head.Hash().String() == pkg.Pin
+		if !isLatest {
+			launchr.Term().Info().Printfln("Checking out locked commit %s of %s package", pkg.Pin, pkg.GetName())
+		}
+
+		return isLatest, nil
+	}
+
 	headName := head.Name().Short()
-	pkgRefName := pkg.GetRef()
+	pkgRefName := pkg.GetResolvedRef()
 	remoteRefName := pkgRefName
 
 	if pkg.GetTarget() == TargetLatest && headName != "" {
@@ -153,7 +112,7 @@ func (g *gitDownloader) EnsureLatest(pkg *Package, downloadPath string) (bool, e
 	isLatest := false
 	if headName == pkgRefName {
 		pullTarget = "branch"
-		isLatest, err = g.ensureLatestBranch(r, pkg.GetURL(), pkgRefName, remoteRefName)
+		isLatest, err = g.ensureLatestBranch(r, pkg.GetURL(), pkgRefName, remoteRefName, head.Hash())
 		if err != nil {
 			launchr.Term().Warning().Printfln("Couldn't check local branch, marking package %s(%s) as outdated, see debug for detailed error.", pkg.GetName(), pkgRefName)
 			launchr.Log().Debug("ensure branch error", "err", err)
@@ -176,7 +135,7 @@ func (g *gitDownloader) EnsureLatest(pkg *Package, downloadPath string) (bool, e
 	return isLatest, nil
 }
 
-func (g *gitDownloader) ensureLatestBranch(r *git.Repository, fetchURL, refName, remoteRefName string) (bool, error) {
+func (g *gitDownloader) ensureLatestBranch(r *git.Repository, fetchURL, refName, remoteRefName string, headHash plumbing.Hash) (bool, error) {
 	refSpec := []config.RefSpec{config.RefSpec(fmt.Sprintf("refs/heads/%s:refs/heads/%s", refName, refName))}
 	err := g.fetchRemotes(r, fetchURL, refSpec)
 	if err != nil {
@@ -199,7 +158,8 @@ func (g *gitDownloader) ensureLatestBranch(r *git.Repository, fetchURL, refName,
 		return false, err
 	}
 
-	return localRef.Hash() == remoteRef.Hash(), nil
+	// Local branch may be reset to a locked commit, ensure HEAD is at the latest commit too.
+	return localRef.Hash() == remoteRef.Hash() && localRef.Hash() == headHash, nil
 }
 
 func (g *gitDownloader) ensureLatestTag(r *git.Repository, fetchURL, refName string) (bool, error) {
@@ -250,7 +210,7 @@ func (g *gitDownloader) Download(ctx context.Context, pkg *Package, targetDir st
 		return errNoURL
 	}
 
-	ref := pkg.GetRef()
+	ref := pkg.GetResolvedRef()
 	if ref == "" {
 		// Try to clone latest master branch.
 		err := g.tryDownload(ctx, targetDir, g.buildOptions(url))
@@ -258,7 +218,7 @@ func (g *gitDownloader) Download(ctx context.Context, pkg *Package, targetDir st
 			return err
 		}
 
-		return nil
+		return g.checkoutPin(pkg, targetDir)
 	}
 
 	loaded := false
@@ -287,7 +247,180 @@ func (g *gitDownloader) Download(ctx context.Context, pkg *Package, targetDir st
 		return fmt.Errorf("couldn't find remote ref %s", ref)
 	}
 
-	return nil
+	return g.checkoutPin(pkg, targetDir)
+}
+
+// checkoutPin resets cloned repository to the locked commit of package.
+func (g *gitDownloader) checkoutPin(pkg *Package, repoPath string) error {
+	if pkg.Pin == "" {
+		return nil
+	}
+
+	r, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return err
+	}
+
+	hash := plumbing.NewHash(pkg.Pin)
+	if _, err = r.CommitObject(hash); err != nil {
+		launchr.Log().Debug("locked commit lookup error", "err", err)
+		return fmt.Errorf("locked commit %s of %s package is not found in ref '%s'", pkg.Pin, pkg.GetName(), pkg.GetTarget())
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	return w.Reset(&git.ResetOptions{Commit: hash, Mode: git.HardReset})
+}
+
+// checkoutRef checks out ref of package in repository restored from cache, as if it was cloned from the ref.
+// Restored repository may be cloned from other ref of the same commit. Tags are preferred over branches
+// as in Download, missing tag is fetched from remote. Locked packages are checked by commit only,
+// their restored HEAD is kept as well as HEAD of packages without ref.
+func (g *gitDownloader) checkoutRef(pkg *Package, repoPath string) error {
+	ref := pkg.GetResolvedRef()
+	if ref == "" || pkg.Pin != "" {
+		return nil
+	}
+
+	r, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+
+	tagName := plumbing.NewTagReferenceName(ref)
+	_, err = r.Reference(tagName, false)
+	if err != nil {
+		refSpec := []config.RefSpec{config.RefSpec(fmt.Sprintf("%s:%s", tagName, tagName))}
+		err = g.fetchRemotes(r, pkg.GetURL(), refSpec)
+		if err != nil && !errors.Is(err, git.NoMatchingRefSpecError{}) {
+			return err
+		}
+	}
+
+	if err == nil {
+		commit, errResolve := r.ResolveRevision(plumbing.Revision(tagName))
+		if errResolve != nil {
+			return errResolve
+		}
+
+		if *commit != head.Hash() {
+			return fmt.Errorf("tag %s of cached %s package doesn't point to %s", ref, pkg.GetName(), head.Hash())
+		}
+
+		// Tags are cloned with detached HEAD.
+		return r.Storer.SetReference(plumbing.NewHashReference(plumbing.HEAD, head.Hash()))
+	}
+
+	branchName := plumbing.NewBranchReferenceName(ref)
+	refs := []*plumbing.Reference{
+		plumbing.NewHashReference(branchName, head.Hash()),
+		plumbing.NewHashReference(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, ref), head.Hash()),
+		plumbing.NewSymbolicReference(plumbing.HEAD, branchName),
+	}
+
+	for _, rf := range refs {
+		if err = r.Storer.SetReference(rf); err != nil {
+			return err
+		}
+	}
+
+	if _, err = r.Branch(ref); err == nil {
+		return nil
+	}
+
+	return r.CreateBranch(&config.Branch{Name: ref, Remote: git.DefaultRemoteName, Merge: branchName})
+}
+
+// listTags returns tags of remote repository.
+func (g *gitDownloader) listTags(url string) ([]string, error) {
+	refs, err := g.listRefs(url, git.IgnorePeeled)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	for _, ref := range refs {
+		if ref.Name().IsTag() {
+			tags = append(tags, ref.Name().Short())
+		}
+	}
+
+	return tags, nil
+}
+
+// listRefs returns references of remote repository without cloning it.
+// Peeling option sets if commits of annotated tags are listed as tag^{} references.
+func (g *gitDownloader) listRefs(url string, peeling git.PeelingOption) ([]*plumbing.Reference, error) {
+	rem := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{url}})
+	options := &git.ListOptions{PeelingOption: peeling}
+
+	var refs []*plumbing.Reference
+	err := g.withAuth(url, func(auth transport.AuthMethod) error {
+		options.Auth = auth
+		var err error
+		refs, err = rem.List(options)
+		return err
+	})
+
+	return refs, err
+}
+
+// remoteRevision resolves ref of package to commit of remote repository without cloning it.
+// Tags are preferred over branches as in Download, annotated tags are resolved to their commits.
+func (g *gitDownloader) remoteRevision(pkg *Package) (string, error) {
+	refs, err := g.listRefs(pkg.GetURL(), git.AppendPeeled)
+	if err != nil {
+		return "", err
+	}
+
+	hashes := make(map[plumbing.ReferenceName]string)
+	head := plumbing.HEAD
+	for _, r := range refs {
+		switch {
+		case r.Type() == plumbing.HashReference:
+			hashes[r.Name()] = r.Hash().String()
+		case r.Name() == plumbing.HEAD:
+			head = r.Target()
+		}
+	}
+
+	names := []plumbing.ReferenceName{head}
+	ref := pkg.GetResolvedRef()
+	if ref != "" {
+		tag := plumbing.NewTagReferenceName(ref)
+		names = []plumbing.ReferenceName{tag + "^{}", tag, plumbing.NewBranchReferenceName(ref)}
+	}
+
+	for _, name := range names {
+		if hash, ok := hashes[name]; ok {
+			return hash, nil
+		}
+	}
+
+	return "", fmt.Errorf("couldn't find remote ref %s", ref)
+}
+
+// Revision implements Downloader.Revision interface, returns commit of local HEAD.
+func (g *gitDownloader) Revision(_ *Package, downloadPath string) (string, error) {
+	r, err := git.PlainOpen(downloadPath)
+	if err != nil {
+		return "", err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+
+	return head.Hash().String(), nil
 }
 
 func (g *gitDownloader) buildOptions(url string) *git.CloneOptions {
@@ -299,69 +432,164 @@ func (g *gitDownloader) buildOptions(url string) *git.CloneOptions {
 }
 
 func (g *gitDownloader) tryDownload(ctx context.Context, targetDir string, options *git.CloneOptions) error {
-	url := options.URL
+	return g.withAuth(options.URL, func(auth transport.AuthMethod) error {
+		options.Auth = auth
+		_, err := git.PlainCloneContext(ctx, targetDir, false, options)
+		launchr.Term().Println("")
+		return err
+	})
+}
+
+// withAuth calls fn with auth methods in order: without credentials, with credentials from keyring
+// and with manually entered credentials. Next method is tried only if remote rejected the previous one.
+func (g *gitDownloader) withAuth(url string, fn func(auth transport.AuthMethod) error) error {
 	auths := []authorizationMode{authorisationNone, authorisationKeyring, authorisationManual}
 	for _, authType := range auths {
-		if authType == authorisationNone {
-			_, err := git.PlainCloneContext(ctx, targetDir, false, options)
-			launchr.Term().Println("")
-			if err != nil {
-				if errors.Is(err, transport.ErrAuthenticationRequired) {
-					launchr.Term().Println("auth required, trying keyring authorisation")
-					continue
+		var ci keyring.CredentialsItem
+		var err error
+		switch authType {
+		case authorisationNone:
+			methods := g.noneAuth(url)
+			for _, auth := range methods {
+				err = fn(auth)
+				if err == nil || !isAuthError(err) {
+					return err
 				}
-
-				return err
 			}
-		}
 
-		if authType == authorisationKeyring {
-			ci, err := g.k.getForURL(url)
-			if err != nil {
-				return err
+			if len(methods) > 0 {
+				launchr.Term().Println("auth required, trying keyring authorisation")
 			}
 
-			options.Auth = &http.BasicAuth{
-				Username: ci.Username,
-				Password: ci.Password,
-			}
+			continue
+		case authorisationKeyring:
+			ci, err = g.k.getForItem(g.credentialsItem(url))
+		case authorisationManual:
+			ci, err = g.k.fillCredentials(g.credentialsItem(url))
+		}
 
-			_, err = git.PlainCloneContext(ctx, targetDir, false, options)
-			if err != nil {
-				if errors.Is(err, transport.ErrAuthorizationFailed) || errors.Is(err, transport.ErrAuthenticationRequired) {
-					if g.k.interactive {
-						launchr.Term().Println("invalid auth, trying manual authorisation")
-						continue
-					}
-				}
+		if err != nil {
+			return err
+		}
 
-				return err
-			}
+		auth, err := g.credentialsAuth(url, ci)
+		if err != nil {
+			return err
 		}
 
-		if authType == authorisationManual {
-			ci := keyring.CredentialsItem{}
-			ci.URL = url
-			ci, err := g.k.fillCredentials(ci)
-			if err != nil {
-				return err
-			}
+		err = fn(auth)
+		if err != nil && authType == authorisationKeyring && isAuthError(err) && g.k.interactive {
+			launchr.Term().Println("invalid auth, trying manual authorisation")
+			continue
+		}
 
-			options.Auth = &http.BasicAuth{
-				Username: ci.Username,
-				Password: ci.Password,
-			}
+		return err
+	}
 
-			_, err = git.PlainCloneContext(ctx, targetDir, false, options)
-			if err != nil {
-				return err
-			}
+	return nil
+}
+
+// isSSHURL checks if git URL uses SSH transport, including scp-like git@host:path URLs.
+func isSSHURL(url string) bool {
+	ep, err := transport.NewEndpoint(url)
+	return err == nil && ep.Protocol == "ssh"
+}
+
+func sshUser(url string) string {
+	ep, err := transport.NewEndpoint(url)
+	if err != nil || ep.User == "" {
+		return ssh.DefaultUsername
+	}
+
+	return ep.User
+}
+
+// sshKeyFile returns configured private key or the first existing default one.
+func (g *gitDownloader) sshKeyFile() string {
+	if g.k.sshKey != "" {
+		return g.k.sshKey
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		keyFile := filepath.Join(home, ".ssh", name)
+		if exists(keyFile) {
+			return keyFile
 		}
+	}
 
-		break
+	return ""
+}
+
+// credentialsItem returns credentials item to request, SSH username is taken from URL.
+func (g *gitDownloader) credentialsItem(url string) keyring.CredentialsItem {
+	ci := keyring.CredentialsItem{URL: url}
+	if isSSHURL(url) {
+		ci.Username = sshUser(url)
 	}
 
-	return nil
+	return ci
+}
+
+// noneAuth returns auth methods tried in order before credentials are requested.
+// SSH URLs are authenticated with ssh-agent and then with private key without passphrase,
+// empty list is returned if none of them is available.
+func (g *gitDownloader) noneAuth(url string) []transport.AuthMethod {
+	if !isSSHURL(url) {
+		return []transport.AuthMethod{nil}
+	}
+
+	var auths []transport.AuthMethod
+	user := sshUser(url)
+	agentAuth, err := ssh.NewSSHAgentAuth(user)
+	if err == nil {
+		auths = append(auths, agentAuth)
+	} else {
+		launchr.Log().Debug("ssh-agent is not available", "err", err)
+	}
+
+	keyFile := g.sshKeyFile()
+	if keyFile == "" {
+		return auths
+	}
+
+	keyAuth, err := ssh.NewPublicKeysFromFile(user, keyFile, "")
+	if err != nil {
+		launchr.Log().Debug("private key requires passphrase", "key", keyFile, "err", err)
+		return auths
+	}
+
+	return append(auths, keyAuth)
+}
+
+// credentialsAuth returns basic auth for http URLs and private key auth for SSH URLs.
+// For SSH URLs credentials password is used as private key passphrase.
+// Host keys of SSH servers are verified using known_hosts files.
+func (g *gitDownloader) credentialsAuth(url string, ci keyring.CredentialsItem) (transport.AuthMethod, error) {
+	if !isSSHURL(url) {
+		return &http.BasicAuth{
+			Username: ci.Username,
+			Password: ci.Password,
+		}, nil
+	}
+
+	keyFile := g.sshKeyFile()
+	if keyFile == "" {
+		return nil, errNoSSHKey
+	}
+
+	return ssh.NewPublicKeysFromFile(sshUser(url), keyFile, ci.Password)
+}
+
+// isAuthError checks if remote rejected missing or invalid credentials.
+func isAuthError(err error) bool {
+	return errors.Is(err, transport.ErrAuthenticationRequired) ||
+		errors.Is(err, transport.ErrAuthorizationFailed) ||
+		strings.Contains(err.Error(), "unable to authenticate")
 }
 
 type authorizationMode int
@@ -0,0 +1,102 @@
+package compose
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// testSSHAgent serves empty ssh-agent on unix socket and returns its path.
+func testSSHAgent(t *testing.T) string {
+	t.Helper()
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { l.Close() }) //nolint
+	go func() {
+		for {
+			conn, errAccept := l.Accept()
+			if errAccept != nil {
+				return
+			}
+
+			go agent.ServeAgent(agent.NewKeyring(), conn) //nolint
+		}
+	}()
+
+	return sock
+}
+
+func testSSHKeyFile(t *testing.T) string {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := gossh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return keyFile
+}
+
+func TestNoneAuth(t *testing.T) {
+	agentSock := testSSHAgent(t)
+	keyFile := testSSHKeyFile(t)
+	home := t.TempDir()
+
+	tests := []struct {
+		name   string
+		url    string
+		agent  string
+		sshKey string
+		want   []string
+	}{
+		{name: "http", url: "https://example.com/repo.git", want: []string{""}},
+		{name: "agent then key file", url: "git@example.com:repo.git", agent: agentSock, sshKey: keyFile, want: []string{ssh.PublicKeysCallbackName, ssh.PublicKeysName}},
+		{name: "agent only", url: "ssh://git@example.com/repo.git", agent: agentSock, want: []string{ssh.PublicKeysCallbackName}},
+		{name: "key file without agent", url: "git@example.com:repo.git", sshKey: keyFile, want: []string{ssh.PublicKeysName}},
+		{name: "nothing available", url: "git@example.com:repo.git"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SSH_AUTH_SOCK", tt.agent)
+			t.Setenv("HOME", home)
+			g := &gitDownloader{k: &keyringWrapper{sshKey: tt.sshKey}}
+			got := g.noneAuth(tt.url)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d auth methods, want %d", len(got), len(tt.want))
+			}
+
+			for i, auth := range got {
+				name := ""
+				if auth != nil {
+					name = auth.Name()
+				}
+
+				if name != tt.want[i] {
+					t.Errorf("auth method %d = %q, want %q", i, name, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"archive/zip"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -13,6 +15,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"text/template"
 
 	"github.com/launchrctl/keyring"
 	"github.com/launchrctl/launchr"
@@ -26,6 +29,24 @@ var (
 	errAuthenticationRequired = errors.New("authentication required")
 	errAuthorizationFailed    = errors.New("authorization failed")
 	errHTTPUnknown            = errors.New("unhandled error")
+	errInvalidAuthHeader      = errors.New("invalid auth header, expected format is 'Name: value'")
+	errInvalidChecksum        = errors.New("invalid checksum, expected format is 'sha256:<hex>'")
+)
+
+const (
+	// HTTPAuthBasic is const for http basic authentication.
+	HTTPAuthBasic = "basic"
+	// HTTPAuthBearer is const for http bearer token authentication.
+	HTTPAuthBearer = "bearer"
+	// HTTPAuthHeader is const for http authentication with custom header.
+	HTTPAuthHeader = "header"
+)
+
+const (
+	// authTokenUsername is a placeholder username of keyring credentials storing only a token.
+	authTokenUsername = "token"
+	// checksumSHA256 is the only supported checksum algorithm of http packages.
+	checksumSHA256 = "sha256"
 )
 
 var (
@@ -42,13 +63,33 @@ func newHTTP(kw *keyringWrapper) Downloader {
 	return &httpDownloader{k: kw}
 }
 
-func (h *httpDownloader) EnsureLatest(_ *Package, downloadPath string) (bool, error) {
-	if _, err := os.Stat(downloadPath); !os.IsNotExist(err) {
-		// Skip download if package exists.
-		return true, nil
+func (h *httpDownloader) EnsureLatest(pkg *Package, downloadPath string) (bool, error) {
+	if _, err := os.Stat(downloadPath); os.IsNotExist(err) {
+		return false, nil
+	}
+
+	if pkg.Pin != "" {
+		digest, err := contentDigest(downloadPath)
+		if err != nil {
+			return false, err
+		}
+
+		return digest == pkg.Pin, nil
 	}
 
-	return false, nil
+	// Checksum is verified only on download, extracted content isn't pinned to the verified archive,
+	// e.g. when checksum changed since the package was locked.
+	if pkg.Source.Checksum != "" {
+		return false, nil
+	}
+
+	// Skip download if package exists.
+	return true, nil
+}
+
+// Revision implements Downloader.Revision interface, returns content digest of downloaded package.
+func (h *httpDownloader) Revision(_ *Package, downloadPath string) (string, error) {
+	return contentDigest(downloadPath)
 }
 
 // Download implements Downloader.Download interface
@@ -59,10 +100,15 @@ func (h *httpDownloader) Download(_ context.Context, pkg *Package, targetDir str
 		return errNoURL
 	}
 
+	checksum, err := parseChecksum(pkg.Source.Checksum)
+	if err != nil {
+		return fmt.Errorf("package %s: %w", pkg.GetName(), err)
+	}
+
 	launchr.Term().Printfln("http download: %s", name)
 	fpath := filepath.Clean(filepath.Join(targetDir, name))
 
-	err := os.MkdirAll(targetDir, dirPermissions)
+	err = os.MkdirAll(targetDir, dirPermissions)
 	if err != nil {
 		return err
 	}
@@ -78,16 +124,146 @@ func (h *httpDownloader) Download(_ context.Context, pkg *Package, targetDir str
 		}
 	}()
 
+	resp, err := h.request(pkg, name)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err = resp.Body.Close(); err != nil {
+			launchr.Log().Debug(errFailedClose.Error())
+		}
+	}()
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, hash), resp.Body)
+	if err != nil {
+		return err
+	}
+
+	// Verify archive before extraction, so tampered content never gets to the packages dir.
+	if sum := hex.EncodeToString(hash.Sum(nil)); checksum != "" && sum != checksum {
+		if errRemove := os.Remove(fpath); errRemove != nil {
+			launchr.Log().Debug("error removing package archive", "path", fpath, "err", errRemove)
+		}
+
+		return fmt.Errorf("checksum mismatch of package %s: expected %s:%s, got %s:%s", pkg.GetName(), checksumSHA256, checksum, checksumSHA256, sum)
+	}
+
+	var archiveRootDir string
+	switch at := rgxArchiveType.FindString(name); at {
+	case "tar.gz":
+		archiveRootDir, err = untar(fpath, targetDir)
+	case "zip":
+		archiveRootDir, err = unzip(fpath, targetDir)
+	default:
+		err = fmt.Errorf("not supported archive type: %s", at)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if archiveRootDir != "" {
+		defer os.Remove(fpath)
+
+		// rename root folder to package name
+		return os.Rename(
+			filepath.Join(targetDir, archiveRootDir),
+			filepath.Join(targetDir, pkg.GetTarget()),
+		)
+	}
+
+	return nil
+}
+
+// ComputeChecksum downloads archive of http dependency and returns its checksum in format 'sha256:<hex>'.
+func ComputeChecksum(dep *Dependency, k keyring.Keyring, interactive bool) (string, error) {
+	kw := &keyringWrapper{keyringService: k, interactive: interactive}
+	h := &httpDownloader{k: kw}
+	pkg := dep.ToPackage(dep.Name)
+	name := rgxNameFromURL.FindString(pkg.GetURL())
+	if name == "" {
+		return "", errNoURL
+	}
+
+	launchr.Term().Printfln("Computing checksum of %s", name)
+	resp, err := h.request(pkg, name)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, resp.Body); err != nil {
+		return "", err
+	}
+
+	if kw.shouldUpdate {
+		if err = k.Save(); err != nil {
+			return "", err
+		}
+	}
+
+	return checksumSHA256 + ":" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// parseChecksum validates checksum in format 'sha256:<hex>' and returns its hex part.
+func parseChecksum(checksum string) (string, error) {
+	if checksum == "" {
+		return "", nil
+	}
+
+	algo, sum, found := strings.Cut(checksum, ":")
+	if !found || strings.ToLower(algo) != checksumSHA256 {
+		return "", errInvalidChecksum
+	}
+
+	sum = strings.ToLower(sum)
+	if _, err := hex.DecodeString(sum); err != nil || len(sum) != sha256.Size*2 {
+		return "", errInvalidChecksum
+	}
+
+	return sum, nil
+}
+
+// ValidateChecksum checks that checksum is empty or in format 'sha256:<hex>'.
+func ValidateChecksum(checksum string) error {
+	_, err := parseChecksum(checksum)
+	return err
+}
+
+// request sends package request. Basic auth credentials are applied if remote requires authentication,
+// token of other auth modes is always sent.
+func (h *httpDownloader) request(pkg *Package, name string) (*http.Response, error) {
+	url := pkg.GetURL()
 	client := &http.Client{}
 	var resp *http.Response
+	var err error
 
 	errDownloadFailed := fmt.Errorf("failed to download package: %s", name)
 
+	authMode := pkg.GetAuth()
+	switch authMode {
+	case HTTPAuthBasic, HTTPAuthBearer:
+	case HTTPAuthHeader:
+		if _, _, errHeader := parseAuthHeader(pkg.Source.Header); errHeader != nil {
+			return nil, fmt.Errorf("package %s: %w", pkg.GetName(), errHeader)
+		}
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q of package %s", authMode, pkg.GetName())
+	}
+
 	auths := []authorizationMode{authorisationNone, authorisationKeyring, authorisationManual}
+	if authMode != HTTPAuthBasic {
+		// Token is sent upfront, private assets may respond with 404 to anonymous request.
+		auths = auths[1:]
+	}
+
 	for _, authType := range auths {
 		req, errReq := http.NewRequest(http.MethodGet, url, nil)
 		if errReq != nil {
-			return errReq
+			return nil, errReq
 		}
 
 		if authType == authorisationNone {
@@ -99,17 +275,20 @@ func (h *httpDownloader) Download(_ context.Context, pkg *Package, targetDir str
 				}
 
 				launchr.Log().Debug(err.Error())
-				return errDownloadFailed
+				return nil, errDownloadFailed
 			}
 		}
 
 		if authType == authorisationKeyring {
-			ci, errGet := h.k.getForURL(url)
+			ci, errGet := h.k.getForItem(h.credentialsItem(pkg))
 			if errGet != nil {
-				return errGet
+				return nil, errGet
+			}
+
+			if err = setRequestAuth(req, pkg, ci); err != nil {
+				return nil, err
 			}
 
-			req.SetBasicAuth(ci.Username, ci.Password)
 			resp, err = doRequest(client, req)
 			if err != nil {
 				if errors.Is(err, errAuthorizationFailed) {
@@ -120,71 +299,93 @@ func (h *httpDownloader) Download(_ context.Context, pkg *Package, targetDir str
 				}
 
 				launchr.Log().Debug(err.Error())
-				return errDownloadFailed
+				return nil, errDownloadFailed
 			}
 		}
 
 		if authType == authorisationManual {
-			ci := keyring.CredentialsItem{}
-			ci.URL = url
-			ci, errFill := h.k.fillCredentials(ci)
+			ci, errFill := h.k.fillCredentials(h.credentialsItem(pkg))
 			if errFill != nil {
-				return errFill
+				return nil, errFill
+			}
+
+			if err = setRequestAuth(req, pkg, ci); err != nil {
+				return nil, err
 			}
 
-			req.SetBasicAuth(ci.Username, ci.Password)
 			resp, err = doRequest(client, req)
 			if err != nil {
 				launchr.Log().Debug(err.Error())
-				return errDownloadFailed
+				return nil, errDownloadFailed
 			}
 		}
 
 		break
 	}
 
-	defer func() {
-		if err = resp.Body.Close(); err != nil {
-			launchr.Log().Debug(errFailedClose.Error())
-		}
-	}()
+	return resp, nil
+}
 
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
-		return err
+// credentialsItem returns credentials item to request. Token is stored as a password,
+// so username isn't requested when it's not used.
+func (h *httpDownloader) credentialsItem(pkg *Package) keyring.CredentialsItem {
+	ci := keyring.CredentialsItem{URL: pkg.GetURL()}
+	switch pkg.GetAuth() {
+	case HTTPAuthBearer:
+		ci.Username = authTokenUsername
+	case HTTPAuthHeader:
+		if !strings.Contains(pkg.Source.Header, ".Username") {
+			ci.Username = authTokenUsername
+		}
 	}
 
-	var archiveRootDir string
-	switch at := rgxArchiveType.FindString(name); at {
-	case "tar.gz":
-		archiveRootDir, err = untar(fpath, targetDir)
-	case "zip":
-		archiveRootDir, err = unzip(fpath, targetDir)
+	return ci
+}
+
+// setRequestAuth applies credentials to request according to package auth mode.
+func setRequestAuth(req *http.Request, pkg *Package, ci keyring.CredentialsItem) error {
+	switch pkg.GetAuth() {
+	case HTTPAuthBearer:
+		req.Header.Set("Authorization", "Bearer "+ci.Password)
+	case HTTPAuthHeader:
+		name, tpl, err := parseAuthHeader(pkg.Source.Header)
+		if err != nil {
+			return err
+		}
+
+		var value strings.Builder
+		if err = tpl.Execute(&value, ci); err != nil {
+			return fmt.Errorf("can't render auth header of package %s: %w", pkg.GetName(), err)
+		}
+
+		req.Header.Set(name, value.String())
 	default:
-		err = fmt.Errorf("not supported archive type: %s", at)
+		req.SetBasicAuth(ci.Username, ci.Password)
 	}
 
-	if err != nil {
-		return err
-	}
+	return nil
+}
 
-	if archiveRootDir != "" {
-		defer os.Remove(fpath)
+// parseAuthHeader parses header in format 'Name: value', value is a template
+// with credentials fields, e.g. 'PRIVATE-TOKEN: {{ .Password }}'.
+func parseAuthHeader(header string) (string, *template.Template, error) {
+	name, value, found := strings.Cut(header, ":")
+	name = strings.TrimSpace(name)
+	if !found || name == "" {
+		return "", nil, errInvalidAuthHeader
+	}
 
-		// rename root folder to package name
-		return os.Rename(
-			filepath.Join(targetDir, archiveRootDir),
-			filepath.Join(targetDir, pkg.GetTarget()),
-		)
+	tpl, err := template.New("header").Option("missingkey=error").Parse(strings.TrimSpace(value))
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %w", errInvalidAuthHeader, err)
 	}
 
-	return nil
+	return name, tpl, nil
 }
 
 func doRequest(client *http.Client, req *http.Request) (*http.Response, error) {
 	resp, err := client.Do(req)
 	if err != nil {
-		resp.Body.Close() //nolint
 		return nil, err
 	}
 
@@ -192,6 +393,11 @@ func doRequest(client *http.Client, req *http.Request) (*http.Response, error) {
 		return resp, nil
 	}
 
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		resp.Body.Close() //nolint
+	}
+
 	switch resp.StatusCode {
 	case http.StatusUnauthorized:
 		return nil, errAuthenticationRequired
@@ -0,0 +1,286 @@
+package compose
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/launchrctl/keyring"
+)
+
+type testKeyring struct {
+	keyring.Keyring
+	items map[string]keyring.CredentialsItem
+}
+
+func (k *testKeyring) GetForURL(url string) (keyring.CredentialsItem, error) {
+	ci, ok := k.items[url]
+	if !ok {
+		return ci, keyring.ErrNotFound
+	}
+
+	return ci, nil
+}
+
+func newTestDownloader(url string, ci keyring.CredentialsItem) *httpDownloader {
+	ci.URL = url
+	k := &testKeyring{items: map[string]keyring.CredentialsItem{url: ci}}
+	return &httpDownloader{k: &keyringWrapper{keyringService: k}}
+}
+
+type testArchiveFile struct {
+	name string
+	body string
+}
+
+func tarGzArchive(t *testing.T, files []testArchiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(f.body))}
+		if f.name[len(f.name)-1] == '/' {
+			hdr = &tar.Header{Name: f.name, Mode: 0755, Typeflag: tar.TypeDir}
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write([]byte(f.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func zipArchive(t *testing.T, files []testArchiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = w.Write([]byte(f.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestHTTPRequestAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		auth       string
+		header     string
+		ci         keyring.CredentialsItem
+		authorized func(r *http.Request) bool
+		anonymous  int
+		wantAnon   bool
+	}{
+		{
+			name: "basic auth after 401",
+			auth: "",
+			ci:   keyring.CredentialsItem{Username: "user", Password: "pass"},
+			authorized: func(r *http.Request) bool {
+				u, p, ok := r.BasicAuth()
+				return ok && u == "user" && p == "pass"
+			},
+			anonymous: http.StatusUnauthorized,
+			wantAnon:  true,
+		},
+		{
+			name: "bearer token is sent upfront",
+			auth: HTTPAuthBearer,
+			ci:   keyring.CredentialsItem{Username: authTokenUsername, Password: "secret"},
+			authorized: func(r *http.Request) bool {
+				return r.Header.Get("Authorization") == "Bearer secret"
+			},
+			anonymous: http.StatusNotFound,
+		},
+		{
+			name:   "custom header is sent upfront",
+			auth:   HTTPAuthHeader,
+			header: "PRIVATE-TOKEN: {{ .Password }}",
+			ci:     keyring.CredentialsItem{Username: authTokenUsername, Password: "secret"},
+			authorized: func(r *http.Request) bool {
+				return r.Header.Get("PRIVATE-TOKEN") == "secret"
+			},
+			anonymous: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anonRequests := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.authorized(r) {
+					_, _ = w.Write([]byte("content"))
+					return
+				}
+
+				anonRequests++
+				w.WriteHeader(tt.anonymous)
+			}))
+			defer srv.Close()
+
+			url := srv.URL + "/pkg.tar.gz"
+			pkg := &Package{Name: "pkg", Source: Source{Type: HTTPType, URL: url, Auth: tt.auth, Header: tt.header}}
+			resp, err := newTestDownloader(url, tt.ci).request(pkg, "pkg.tar.gz")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp.Body.Close() //nolint
+
+			if tt.wantAnon != (anonRequests > 0) {
+				t.Errorf("anonymous requests = %d, want anonymous request: %v", anonRequests, tt.wantAnon)
+			}
+		})
+	}
+}
+
+func TestHTTPDownload(t *testing.T) {
+	tarGz := tarGzArchive(t, []testArchiveFile{{"pkg-1.0/", ""}, {"pkg-1.0/roles/", ""}, {"pkg-1.0/roles/main.yml", "tasks"}})
+	zipped := zipArchive(t, []testArchiveFile{{"pkg-1.0/", ""}, {"pkg-1.0/roles/main.yml", "tasks"}})
+	evil := tarGzArchive(t, []testArchiveFile{{"pkg-1.0/", ""}, {"../evil.txt", "evil"}})
+	sum := func(data []byte) string {
+		s := sha256.Sum256(data)
+		return checksumSHA256 + ":" + hex.EncodeToString(s[:])
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		data     []byte
+		checksum string
+		wantErr  error
+		wantFail bool
+	}{
+		{name: "tar.gz", file: "pkg.tar.gz", data: tarGz},
+		{name: "zip", file: "pkg.zip", data: zipped},
+		{name: "valid checksum", file: "pkg.tar.gz", data: tarGz, checksum: sum(tarGz)},
+		{name: "checksum mismatch", file: "pkg.tar.gz", data: tarGz, checksum: sum(zipped), wantFail: true},
+		{name: "invalid checksum", file: "pkg.tar.gz", data: tarGz, checksum: "md5:abc", wantErr: errInvalidChecksum},
+		{name: "path traversal", file: "pkg.tar.gz", data: evil, wantErr: errInvalidFilepath},
+		{name: "unsupported archive", file: "pkg.rar", data: tarGz, wantFail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write(tt.data)
+			}))
+			defer srv.Close()
+
+			dir := t.TempDir()
+			url := srv.URL + "/" + tt.file
+			pkg := &Package{Name: "pkg", Source: Source{Type: HTTPType, URL: url, Checksum: tt.checksum}}
+			err := newTestDownloader(url, keyring.CredentialsItem{}).Download(context.Background(), pkg, dir)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantFail:
+				if err == nil {
+					t.Fatal("expected error")
+				}
+
+				if _, errStat := os.Stat(filepath.Join(dir, pkg.GetTarget())); !os.IsNotExist(errStat) {
+					t.Errorf("package must not be extracted")
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				content, errRead := os.ReadFile(filepath.Join(dir, pkg.GetTarget(), "roles", "main.yml"))
+				if errRead != nil || string(content) != "tasks" {
+					t.Errorf("package is not extracted: %v", errRead)
+				}
+			}
+
+			if _, errStat := os.Stat(filepath.Join(filepath.Dir(dir), "evil.txt")); errStat == nil {
+				t.Errorf("archive is extracted outside of target dir")
+			}
+		})
+	}
+}
+
+func TestHTTPChecksumChange(t *testing.T) {
+	archives := [][]byte{
+		tarGzArchive(t, []testArchiveFile{{"pkg-1.0/", ""}, {"pkg-1.0/main.yml", "v1"}}),
+		tarGzArchive(t, []testArchiveFile{{"pkg-1.1/", ""}, {"pkg-1.1/main.yml", "v2"}}),
+	}
+	current, requests := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		_, _ = w.Write(archives[current])
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	kw := &keyringWrapper{keyringService: &testKeyring{}}
+	download := func(lock *YamlLock) *YamlLock {
+		t.Helper()
+		s := sha256.Sum256(archives[current])
+		source := Source{Type: HTTPType, URL: srv.URL + "/pkg.tar.gz", Checksum: checksumSHA256 + ":" + hex.EncodeToString(s[:])}
+		yc := &YamlCompose{Dependencies: []Dependency{{Name: "pkg", Source: source}}}
+		packages, err := CreateDownloadManager(kw, DownloadOptions{Lock: lock}).Download(context.Background(), yc, dir)
+		if err != nil {
+			t.Fatalf("download error: %v", err)
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, "pkg", TargetLatest, "main.yml"))
+		if err != nil || string(content) != fmt.Sprintf("v%d", current+1) {
+			t.Fatalf("unexpected package content %q: %v", content, err)
+		}
+
+		return createLock(packages)
+	}
+
+	lock := download(nil)
+	if lock.Packages[0].Checksum == "" {
+		t.Fatal("checksum is not locked")
+	}
+
+	lock = download(lock)
+	if requests != 1 {
+		t.Fatalf("package with locked checksum is downloaded again")
+	}
+
+	current = 1
+	lock = download(lock)
+	if requests != 2 || lock.Packages[0].Checksum == "" {
+		t.Errorf("package with changed checksum is not downloaded again")
+	}
+}
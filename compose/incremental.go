@@ -0,0 +1,123 @@
+package compose
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/launchrctl/launchr"
+)
+
+// incrementalStats counts changes of incremental build.
+type incrementalStats struct {
+	written   int
+	removed   int
+	unchanged int
+}
+
+// readManifest returns manifest of the previous build or nil if it doesn't exist or is malformed.
+func readManifest(path string) *BuildManifest {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			launchr.Log().Debug("can't read build manifest", "err", err)
+		}
+
+		return nil
+	}
+
+	var m BuildManifest
+	if err = json.Unmarshal(data, &m); err != nil {
+		launchr.Log().Debug("malformed build manifest", "err", err)
+		return nil
+	}
+
+	return &m
+}
+
+// entriesByPath indexes manifest entries by path.
+func (m *BuildManifest) entriesByPath() map[string]*ManifestEntry {
+	entries := make(map[string]*ManifestEntry, len(m.Files))
+	for _, me := range m.Files {
+		entries[me.Path] = me
+	}
+
+	return entries
+}
+
+// isUnchanged checks if path of the previous build matches the new entry and wasn't modified in build dir.
+func isUnchanged(prev, cur *ManifestEntry, destPath string) bool {
+	if prev == nil || prev.Type != cur.Type || prev.Mode != cur.Mode || prev.Hash != cur.Hash || prev.Target != cur.Target {
+		return false
+	}
+
+	info, err := os.Lstat(destPath)
+	if err != nil || manifestType(info.Mode()) != cur.Type {
+		return false
+	}
+
+	if cur.Type == manifestTypeFile {
+		if info.Size() != cur.Size || fmt.Sprintf("%04o", info.Mode().Perm()) != cur.Mode {
+			return false
+		}
+
+		// Edits in build dir may keep file size, content is compared to the recorded hash.
+		hash, err := fileHash(destPath)
+		return err == nil && hash == cur.Hash
+	}
+
+	return true
+}
+
+// prepareDestPath removes path of build dir before it's written again, only directories of the same type are kept.
+// Files are unlinked instead of being rewritten in place, as read-only files copied from sources can't be opened for writing.
+func prepareDestPath(destPath string, mode os.FileMode) error {
+	info, err := os.Lstat(destPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	t := manifestType(mode)
+	if manifestType(info.Mode()) == t && t == manifestTypeDir {
+		return nil
+	}
+
+	return os.RemoveAll(destPath)
+}
+
+// removeStalePaths removes paths of the previous build which are not part of the new one.
+func removeStalePaths(targetDir string, prev, cur map[string]*ManifestEntry) (int, error) {
+	var stale []string
+	for path := range prev {
+		if _, ok := cur[path]; !ok {
+			stale = append(stale, path)
+		}
+	}
+
+	// Remove nested paths before their directories.
+	sort.Sort(sort.Reverse(sort.StringSlice(stale)))
+	for _, path := range stale {
+		if err := os.RemoveAll(filepath.Join(targetDir, filepath.FromSlash(path))); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(stale), nil
+}
+
+func manifestType(mode os.FileMode) string {
+	switch mode & os.ModeType {
+	case os.ModeDir:
+		return manifestTypeDir
+	case os.ModeSymlink:
+		return manifestTypeSymlink
+	default:
+		return manifestTypeFile
+	}
+}
@@ -0,0 +1,97 @@
+package compose
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsUnchanged(t *testing.T) {
+	content := []byte("tasks: []\n")
+	entry := &ManifestEntry{Path: "main.yml", Type: manifestTypeFile, Mode: "0600", Size: int64(len(content)), Hash: contentHash(content)}
+
+	tests := []struct {
+		name  string
+		build []byte
+		mode  os.FileMode
+		want  bool
+	}{
+		{name: "same file", build: content, mode: 0600, want: true},
+		{name: "edited with the same size", build: []byte("tasks: {}\n"), mode: 0600},
+		{name: "edited size", build: []byte("tasks:\n"), mode: 0600},
+		{name: "changed mode", build: content, mode: 0644},
+		{name: "removed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destPath := filepath.Join(t.TempDir(), entry.Path)
+			if tt.build != nil {
+				if err := os.WriteFile(destPath, tt.build, tt.mode); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.Chmod(destPath, tt.mode); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			prev := *entry
+			if got := isUnchanged(&prev, entry, destPath); got != tt.want {
+				t.Errorf("isUnchanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIncrementalBuildReadOnlyFile(t *testing.T) {
+	platformDir := t.TempDir()
+	targetDir := filepath.Join(platformDir, BuildDir)
+	srcPath := filepath.Join(platformDir, "conf.yml")
+	destPath := filepath.Join(targetDir, "conf.yml")
+	prevPath := filepath.Join(t.TempDir(), "conf.yml")
+
+	for i, content := range []string{"a: 1\n", "a: 2\n"} {
+		if err := os.WriteFile(srcPath, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Chmod(srcPath, 0444); err != nil {
+			t.Fatal(err)
+		}
+
+		if i > 0 {
+			// Link keeps file of the previous build, it's changed only if file is rewritten in place.
+			if err := os.Link(destPath, prevPath); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		b := createBuilder(platformDir, targetDir, "", false, false, true, nil)
+		if err := b.build(context.Background()); err != nil {
+			t.Fatalf("build %d: %v", i, err)
+		}
+
+		got, err := os.ReadFile(destPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != content {
+			t.Errorf("build %d: got %q, want %q", i, got, content)
+		}
+
+		// Read-only file must be replaced, it can't be opened for writing by regular user.
+		if i > 0 {
+			prev, errPrev := os.ReadFile(prevPath)
+			if errPrev != nil || string(prev) == content {
+				t.Errorf("build %d: read-only file is rewritten in place", i)
+			}
+		}
+
+		if err = os.Chmod(srcPath, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package compose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+)
+
+const (
+	// WithinAny allows to bump ref to any newer tag.
+	WithinAny = "any"
+	// WithinMajor allows to bump ref to newer tags of the same major version.
+	WithinMajor = "major"
+	// WithinMinor allows to bump ref to newer tags of the same major and minor version.
+	WithinMinor = "minor"
+)
+
+// LatestOptions - list of options to bump packages refs to the newest tags
+type LatestOptions struct {
+	Packages    []string
+	Within      string
+	Constraint  string
+	Interactive bool
+	SSHKey      string
+}
+
+// UpdatePackagesLatest bumps refs of git packages pinned to exact tags to the newest remote tags.
+// Packages with branches or semver ranges as ref are skipped.
+func UpdatePackagesLatest(opts LatestOptions, dir string, k keyring.Keyring) error {
+	config, err := Lookup(os.DirFS(dir))
+	if err != nil {
+		return err
+	}
+
+	var userConstraint *semver.Constraints
+	if opts.Constraint != "" {
+		userConstraint, err = semver.NewConstraint(opts.Constraint)
+		if err != nil {
+			return fmt.Errorf("invalid version constraint %q: %w", opts.Constraint, err)
+		}
+	}
+
+	for _, name := range opts.Packages {
+		if !slices.ContainsFunc(config.Dependencies, func(d Dependency) bool { return d.Name == name }) {
+			return fmt.Errorf("package %s is not found in %s", name, composeFile)
+		}
+	}
+
+	kw := &keyringWrapper{keyringService: k, shouldUpdate: false, interactive: opts.Interactive, sshKey: opts.SSHKey}
+	g := &gitDownloader{k: kw}
+
+	var changes []string
+	for i := range config.Dependencies {
+		dep := &config.Dependencies[i]
+		if len(opts.Packages) > 0 && !slices.Contains(opts.Packages, dep.Name) {
+			continue
+		}
+
+		pkg := dep.ToPackage(dep.Name)
+		ref := pkg.GetRef()
+		if pkg.GetType() != GitType || isVersionConstraint(ref) {
+			launchr.Log().Debug("skipping package, it's not pinned to a tag", "package", dep.Name)
+			continue
+		}
+
+		current, errVer := semver.NewVersion(ref)
+		if errVer != nil {
+			launchr.Log().Debug("skipping package, ref is not a semantic version", "package", dep.Name, "ref", ref)
+			continue
+		}
+
+		tags, errList := g.listTags(pkg.GetURL())
+		if errList != nil {
+			return fmt.Errorf("can't list tags of package %s: %w", dep.Name, errList)
+		}
+
+		if !slices.Contains(tags, ref) {
+			launchr.Log().Debug("skipping package, ref is not a tag", "package", dep.Name, "ref", ref)
+			continue
+		}
+
+		constraints, errWithin := withinConstraints(current, opts.Within)
+		if errWithin != nil {
+			return errWithin
+		}
+
+		if userConstraint != nil {
+			constraints = append(constraints, userConstraint)
+		}
+
+		tag := highestMatchingTag(tags, constraints...)
+		if tag == "" || tag == ref {
+			continue
+		}
+
+		changes = append(changes, fmt.Sprintf("  %s: %s -> %s", dep.Name, ref, tag))
+		// Deprecated tag field is migrated to ref.
+		dep.Source.Ref = tag
+		dep.Source.Tag = ""
+	}
+
+	if kw.shouldUpdate {
+		if err = k.Save(); err != nil {
+			return err
+		}
+	}
+
+	if len(changes) == 0 {
+		launchr.Term().Println("All packages are at the newest tags")
+		return nil
+	}
+
+	launchr.Term().Printfln("Updated packages:")
+	for _, change := range changes {
+		launchr.Term().Println(change)
+	}
+
+	launchr.Term().Println("Saving plasma-compose...")
+	sortPackages(config)
+	err = writeComposeYaml(config)
+	if err != nil {
+		return err
+	}
+
+	if exists(filepath.Join(dir, lockFile)) {
+		launchr.Term().Printfln("Run compose:lock to update %s", lockFile)
+	}
+
+	return nil
+}
+
+// withinConstraints limits newer tags to the same major or minor version of current one.
+func withinConstraints(current *semver.Version, within string) ([]*semver.Constraints, error) {
+	var rng string
+	switch within {
+	case WithinAny, "":
+		rng = fmt.Sprintf(">=%s", current)
+	case WithinMajor:
+		rng = fmt.Sprintf(">=%s, <%d.0.0", current, current.Major()+1)
+	case WithinMinor:
+		rng = fmt.Sprintf(">=%s, <%d.%d.0", current, current.Major(), current.Minor()+1)
+	default:
+		return nil, fmt.Errorf("unknown version boundary %q", within)
+	}
+
+	c, err := semver.NewConstraint(rng)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*semver.Constraints{c}, nil
+}
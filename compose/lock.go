@@ -0,0 +1,217 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/sumdb/dirhash"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	lockFile = "plasma-compose.lock"
+)
+
+var (
+	errLockNotExists    = errors.New("plasma-compose.lock doesn't exist")
+	errLockBadStructure = errors.New("incorrect mapping for plasma-compose.lock, ensure structure is correct")
+)
+
+// YamlLock stores resolved revisions of all packages, including transitive ones.
+type YamlLock struct {
+	Packages []*LockedPackage `yaml:"packages"`
+}
+
+// LockedPackage stores resolved revision of a package.
+type LockedPackage struct {
+	Name         string     `yaml:"name"`
+	Type         string     `yaml:"type"`
+	URL          string     `yaml:"url"`
+	Ref          string     `yaml:"ref,omitempty"`
+	Tag          string     `yaml:"tag,omitempty"`
+	Commit       string     `yaml:"commit,omitempty"`
+	Digest       string     `yaml:"digest,omitempty"`
+	Checksum     string     `yaml:"checksum,omitempty"`
+	Subpath      string     `yaml:"subpath,omitempty"`
+	Target       string     `yaml:"target,omitempty"`
+	Strategies   []Strategy `yaml:"strategy,omitempty"`
+	Dependencies []string   `yaml:"dependencies,omitempty"`
+}
+
+// GetRevision returns locked commit for git packages or content digest for http packages.
+func (lp *LockedPackage) GetRevision() string {
+	if lp.Type == HTTPType {
+		return lp.Digest
+	}
+
+	return lp.Commit
+}
+
+// Matches checks if locked package was resolved from the same source as the package.
+// Archive checksum is a part of http source, locked digest was computed from the archive verified with it.
+func (lp *LockedPackage) Matches(pkg *Package) bool {
+	return lp.Name == pkg.GetName() &&
+		lp.Type == pkg.GetType() &&
+		lp.URL == pkg.GetURL() &&
+		lp.Ref == pkg.GetRef() &&
+		lp.Checksum == pkg.Source.Checksum
+}
+
+// Get returns locked package by name or nil if it's not locked.
+func (l *YamlLock) Get(name string) *LockedPackage {
+	if l == nil {
+		return nil
+	}
+
+	for _, lp := range l.Packages {
+		if lp.Name == name {
+			return lp
+		}
+	}
+
+	return nil
+}
+
+// pin sets locked revision to package if the lock entry matches the package source.
+func (l *YamlLock) pin(pkg *Package) {
+	lp := l.Get(pkg.GetName())
+	if lp != nil && lp.Matches(pkg) {
+		pkg.Pin = lp.GetRevision()
+		pkg.ResolvedRef = lp.Tag
+	}
+}
+
+// verifyPackage checks that package source is the same as locked one.
+func (l *YamlLock) verifyPackage(pkg *Package) error {
+	lp := l.Get(pkg.GetName())
+	if lp == nil {
+		return fmt.Errorf("package %s is not locked", pkg.GetName())
+	}
+
+	if !lp.Matches(pkg) || !equalStrategies(lp.Strategies, pkg.GetStrategies()) || lp.Subpath != pkg.GetSubpath() || lp.Target != pkg.GetMountTarget() {
+		return fmt.Errorf("package %s source differs from locked one", pkg.GetName())
+	}
+
+	return nil
+}
+
+// Verify checks that lock is up to date with compose dependencies.
+func (l *YamlLock) Verify(yc *YamlCompose) error {
+	var errs []string
+	var queue []string
+	for _, dep := range yc.Dependencies {
+		if err := l.verifyPackage(dep.ToPackage(dep.Name)); err != nil {
+			errs = append(errs, err.Error())
+		}
+
+		queue = append(queue, dep.Name)
+	}
+
+	// Ensure lock doesn't keep packages which are not required anymore.
+	required := make(map[string]bool)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if required[name] {
+			continue
+		}
+
+		required[name] = true
+		if lp := l.Get(name); lp != nil {
+			queue = append(queue, lp.Dependencies...)
+		}
+	}
+
+	for _, lp := range l.Packages {
+		if !required[lp.Name] {
+			errs = append(errs, fmt.Sprintf("package %s is locked but not required", lp.Name))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("plasma-compose.lock is out of date, run compose:lock to update it:\n  %s", strings.Join(errs, "\n  "))
+	}
+
+	return nil
+}
+
+// LookupLock allows to search lock file, read and parse it.
+func LookupLock(fsys fs.FS) (*YamlLock, error) {
+	f, err := fs.ReadFile(fsys, lockFile)
+	if err != nil {
+		return &YamlLock{}, errLockNotExists
+	}
+
+	lock := YamlLock{}
+	err = yaml.Unmarshal(f, &lock)
+	if err != nil {
+		return &YamlLock{}, errLockBadStructure
+	}
+
+	return &lock, nil
+}
+
+func createLock(packages []*Package) *YamlLock {
+	lockMap := make(map[string]*LockedPackage)
+	for _, pkg := range packages {
+		lp := &LockedPackage{
+			Name:         pkg.GetName(),
+			Type:         pkg.GetType(),
+			URL:          pkg.GetURL(),
+			Ref:          pkg.GetRef(),
+			Tag:          pkg.ResolvedRef,
+			Subpath:      pkg.GetSubpath(),
+			Target:       pkg.GetMountTarget(),
+			Strategies:   pkg.GetStrategies(),
+			Dependencies: pkg.Dependencies,
+		}
+
+		if lp.Type == HTTPType {
+			lp.Digest = pkg.Revision
+			lp.Checksum = pkg.Source.Checksum
+		} else {
+			lp.Commit = pkg.Revision
+		}
+
+		lockMap[lp.Name] = lp
+	}
+
+	lock := &YamlLock{}
+	for _, lp := range lockMap {
+		lock.Packages = append(lock.Packages, lp)
+	}
+
+	sort.Slice(lock.Packages, func(i, j int) bool {
+		return lock.Packages[i].Name < lock.Packages[j].Name
+	})
+
+	return lock
+}
+
+func writeLock(dir string, lock *YamlLock) error {
+	yamlContent, err := yaml.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("could not marshal lock into YAML: %v", err)
+	}
+
+	return os.WriteFile(filepath.Join(dir, lockFile), yamlContent, os.FileMode(composePermissions))
+}
+
+func equalStrategies(a, b []Strategy) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// contentDigest returns hash of all files in the directory.
+func contentDigest(dir string) (string, error) {
+	return dirhash.HashDir(dir, "", dirhash.Hash1)
+}
@@ -0,0 +1,90 @@
+package compose
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLockVerify(t *testing.T) {
+	lockedApp := func() *LockedPackage {
+		return &LockedPackage{
+			Name:         "app",
+			Type:         GitType,
+			URL:          "https://example.com/app.git",
+			Ref:          "v1.0.0",
+			Commit:       "1111111111111111111111111111111111111111",
+			Strategies:   []Strategy{{Name: StrategyOverwriteLocal, Paths: []string{"roles"}}},
+			Dependencies: []string{"common"},
+		}
+	}
+	lockedCommon := &LockedPackage{Name: "common", Type: GitType, URL: "https://example.com/common.git", Ref: "v2.0.0", Commit: "2222222222222222222222222222222222222222"}
+	appSource := Source{
+		Type:       GitType,
+		URL:        "https://example.com/app.git",
+		Ref:        "v1.0.0",
+		Strategies: []Strategy{{Name: StrategyOverwriteLocal, Paths: []string{"roles"}}},
+	}
+
+	tests := []struct {
+		name    string
+		source  Source
+		locked  []*LockedPackage
+		wantErr string
+	}{
+		{name: "up to date", source: appSource, locked: []*LockedPackage{lockedApp(), lockedCommon}},
+		{name: "package isn't locked", source: appSource, locked: []*LockedPackage{lockedCommon}, wantErr: "package app is not locked"},
+		{
+			name:    "ref changed",
+			source:  Source{Type: GitType, URL: appSource.URL, Ref: "v1.1.0", Strategies: appSource.Strategies},
+			locked:  []*LockedPackage{lockedApp(), lockedCommon},
+			wantErr: "package app source differs from locked one",
+		},
+		{
+			name:    "strategy changed",
+			source:  Source{Type: GitType, URL: appSource.URL, Ref: appSource.Ref},
+			locked:  []*LockedPackage{lockedApp(), lockedCommon},
+			wantErr: "package app source differs from locked one",
+		},
+		{
+			name:    "mount changed",
+			source:  Source{Type: GitType, URL: appSource.URL, Ref: appSource.Ref, Strategies: appSource.Strategies, Target: "app"},
+			locked:  []*LockedPackage{lockedApp(), lockedCommon},
+			wantErr: "package app source differs from locked one",
+		},
+		{
+			name:   "checksum changed",
+			source: Source{Type: HTTPType, URL: "https://example.com/app.tar.gz", Checksum: "sha256:" + strings.Repeat("1", 64)},
+			locked: []*LockedPackage{
+				{Name: "app", Type: HTTPType, URL: "https://example.com/app.tar.gz", Digest: "h1:abc", Checksum: "sha256:" + strings.Repeat("0", 64)},
+			},
+			wantErr: "package app source differs from locked one",
+		},
+		{
+			name:   "transitive package isn't required anymore",
+			source: appSource,
+			locked: []*LockedPackage{
+				func() *LockedPackage { lp := lockedApp(); lp.Dependencies = nil; return lp }(),
+				lockedCommon,
+			},
+			wantErr: "package common is locked but not required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yc := &YamlCompose{Dependencies: []Dependency{{Name: "app", Source: tt.source}}}
+			err := (&YamlLock{Packages: tt.locked}).Verify(yc)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
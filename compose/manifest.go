@@ -0,0 +1,145 @@
+package compose
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// ManifestFile lists files of the build with their origin.
+	ManifestFile = MainDir + "/build.manifest.json"
+)
+
+const (
+	manifestTypeFile    = "file"
+	manifestTypeDir     = "dir"
+	manifestTypeSymlink = "symlink"
+)
+
+// BuildManifest describes every path of the build.
+type BuildManifest struct {
+	Files []*ManifestEntry `json:"files"`
+}
+
+// ManifestEntry is a path of the build with the package it's composed from.
+type ManifestEntry struct {
+	Path     string   `json:"path"`
+	Type     string   `json:"type"`
+	Source   string   `json:"source"`
+	Ref      string   `json:"ref,omitempty"`
+	Revision string   `json:"revision,omitempty"`
+	Mode     string   `json:"mode"`
+	Size     int64    `json:"size,omitempty"`
+	Hash     string   `json:"hash,omitempty"`
+	Target   string   `json:"target,omitempty"`
+	Merged   []string `json:"merged,omitempty"`
+	Patches  []string `json:"patches,omitempty"`
+}
+
+// createManifest describes collected entries, file contents are hashed from sources.
+func createManifest(entries []*fsEntry, packages []*Package) (*BuildManifest, error) {
+	pkgMap := make(map[string]*Package, len(packages))
+	for _, pkg := range packages {
+		pkgMap[pkg.GetName()] = pkg
+	}
+
+	m := &BuildManifest{Files: make([]*ManifestEntry, 0, len(entries))}
+	for _, e := range entries {
+		me, err := createManifestEntry(e, pkgMap[e.From])
+		if err != nil {
+			return nil, err
+		}
+
+		m.Files = append(m.Files, me)
+	}
+
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Path < m.Files[j].Path })
+	return m, nil
+}
+
+func createManifestEntry(e *fsEntry, pkg *Package) (*ManifestEntry, error) {
+	me := &ManifestEntry{
+		Path:   filepath.ToSlash(e.Path),
+		Source: e.From,
+		Mode:   fmt.Sprintf("%04o", e.Entry.Mode().Perm()),
+	}
+
+	if pkg != nil {
+		me.Ref = pkg.GetResolvedRef()
+		me.Revision = pkg.Revision
+	}
+
+	sourcePath := e.sourcePath()
+	switch e.Entry.Mode() & os.ModeType {
+	case os.ModeDir:
+		me.Type = manifestTypeDir
+	case os.ModeSymlink:
+		me.Type = manifestTypeSymlink
+		target, err := os.Readlink(sourcePath)
+		if err != nil {
+			return nil, err
+		}
+
+		me.Target = target
+	default:
+		me.Type = manifestTypeFile
+		if e.Content != nil {
+			// Merged or patched document, packages merged into source and applied patches are listed.
+			me.Size = int64(len(e.Content))
+			me.Hash = contentHash(e.Content)
+			me.Patches = e.Patches
+			for _, m := range e.Merges {
+				me.Merged = append(me.Merged, m.entry.From)
+			}
+
+			break
+		}
+
+		me.Size = e.Entry.Size()
+		hash, err := fileHash(sourcePath)
+		if err != nil {
+			return nil, err
+		}
+
+		me.Hash = hash
+	}
+
+	return me, nil
+}
+
+// contentHash returns sha256 of content in format 'sha256:<hex>'.
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return checksumSHA256 + ":" + hex.EncodeToString(sum[:])
+}
+
+// fileHash returns sha256 of file content in format 'sha256:<hex>'.
+func fileHash(path string) (string, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, f); err != nil {
+		return "", err
+	}
+
+	return checksumSHA256 + ":" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func writeManifest(path string, m *BuildManifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal build manifest: %w", err)
+	}
+
+	return os.WriteFile(path, data, os.FileMode(composePermissions))
+}
@@ -0,0 +1,401 @@
+package compose
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// MergeListsReplace replaces list by list of preferred document.
+	MergeListsReplace = "replace"
+	// MergeListsAppend appends package list items to local list.
+	MergeListsAppend = "append"
+	// MergeListsUnique appends package list items which are not in local list yet.
+	MergeListsUnique = "unique"
+	// MergePrecedenceLocal keeps local values of keys present in both documents.
+	MergePrecedenceLocal = "local"
+	// MergePrecedencePackage takes package values of keys present in both documents.
+	MergePrecedencePackage = "package"
+)
+
+// entryMerge is a package file merged into existing entry by strategy.
+type entryMerge struct {
+	entry    *fsEntry
+	strategy *mergeStrategy
+}
+
+// validateMergeOptions checks list behavior and key precedence of merge strategies.
+func validateMergeOptions(item Strategy) error {
+	switch item.Lists {
+	case "", MergeListsReplace, MergeListsAppend, MergeListsUnique:
+	default:
+		return fmt.Errorf("unknown lists behavior %q of %s strategy, supported are: %s, %s, %s", item.Lists, item.Name, MergeListsReplace, MergeListsAppend, MergeListsUnique)
+	}
+
+	switch item.Precedence {
+	case "", MergePrecedenceLocal, MergePrecedencePackage:
+	default:
+		return fmt.Errorf("unknown precedence %q of %s strategy, supported are: %s, %s", item.Precedence, item.Name, MergePrecedenceLocal, MergePrecedencePackage)
+	}
+
+	return nil
+}
+
+// resolveMerges computes content of entries with merged or appended package files.
+func resolveMerges(entries []*fsEntry) error {
+	for _, e := range entries {
+		if len(e.Merges) == 0 {
+			continue
+		}
+
+		base, err := os.ReadFile(e.sourcePath())
+		if err != nil {
+			return err
+		}
+
+		// Existing entry is the first source, it's processed with strategy of the first merged package.
+		sources := []mergeSource{{e.From, base, e.Merges[0].strategy}}
+		for _, m := range e.Merges {
+			data, errRead := os.ReadFile(m.entry.sourcePath())
+			if errRead != nil {
+				return errRead
+			}
+
+			sources = append(sources, mergeSource{m.entry.From, data, m.strategy})
+		}
+
+		// Domain repo is the root of dependencies graph, its content is appended after packages.
+		if e.From == domainRepo && sources[0].strategy.s == appendFiles {
+			sources = append(sources[1:], sources[0])
+		}
+
+		var content []byte
+		for i, src := range sources {
+			switch {
+			case src.strategy.s == appendFiles:
+				content = appendContent(content, src)
+			case i == 0:
+				content = src.data
+			default:
+				content, err = mergeDocuments(content, src.data, src.strategy)
+				if err != nil {
+					return fmt.Errorf("can't merge %s of package %s into %s: %w", e.Path, src.from, e.From, err)
+				}
+			}
+		}
+
+		e.Content = content
+	}
+
+	return nil
+}
+
+// mergeSource is content of path provided by domain repo or package.
+type mergeSource struct {
+	from     string
+	data     []byte
+	strategy *mergeStrategy
+}
+
+// appendContent concatenates source to content, optionally with header comment and without already present lines.
+func appendContent(content []byte, src mergeSource) []byte {
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		content = append(content, '\n')
+	}
+
+	if src.strategy.header {
+		content = append(content, []byte("# "+src.from+"\n")...)
+	}
+
+	for _, line := range splitContentLines(src.data) {
+		trimmed := strings.TrimSpace(line)
+		if src.strategy.dedup && trimmed != "" && !strings.HasPrefix(trimmed, "#") && containsLine(content, trimmed) {
+			continue
+		}
+
+		content = append(content, line...)
+	}
+
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		content = append(content, '\n')
+	}
+
+	return content
+}
+
+func containsLine(content []byte, line string) bool {
+	for _, l := range splitContentLines(content) {
+		if strings.TrimSpace(l) == line {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeJSONNode writes node as compact JSON, keys of mappings keep their order.
+func writeJSONNode(buf *bytes.Buffer, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+
+		return writeJSONNode(buf, n.Content[0])
+	case yaml.AliasNode:
+		return writeJSONNode(buf, n.Alias)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			if err := writeJSONValue(buf, n.Content[i].Value); err != nil {
+				return err
+			}
+
+			buf.WriteByte(':')
+			if err := writeJSONNode(buf, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range n.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			if err := writeJSONNode(buf, item); err != nil {
+				return err
+			}
+		}
+
+		buf.WriteByte(']')
+	default:
+		// Numbers are kept as written in source document.
+		if (n.Tag == "!!int" || n.Tag == "!!float") && json.Valid([]byte(n.Value)) {
+			buf.WriteString(n.Value)
+			return nil
+		}
+
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return err
+		}
+
+		return writeJSONValue(buf, v)
+	}
+
+	return nil
+}
+
+func writeJSONValue(buf *bytes.Buffer, v any) error {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+
+	// Encoder terminates every value with new line.
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// mergeDocuments deep merges package document into local one. JSON is parsed as YAML, it's a subset of it.
+// Multi-document YAML is merged document by document.
+func mergeDocuments(local, pkg []byte, ms *mergeStrategy) ([]byte, error) {
+	lds, err := decodeDocuments(local)
+	if err != nil {
+		return nil, err
+	}
+
+	pds, err := decodeDocuments(pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	if ms.s == mergeJSONFiles {
+		if len(lds) > 1 || len(pds) > 1 {
+			return nil, errors.New("JSON file must contain a single document")
+		}
+
+		merged := mergeNodes(documentAt(lds, 0), documentAt(pds, 0), ms)
+		if merged == nil {
+			return local, nil
+		}
+
+		var compact, out bytes.Buffer
+		if err = writeJSONNode(&compact, merged); err != nil {
+			return nil, err
+		}
+
+		if err = json.Indent(&out, compact.Bytes(), "", "  "); err != nil {
+			return nil, err
+		}
+
+		return append(out.Bytes(), '\n'), nil
+	}
+
+	if len(lds) == 0 && len(pds) == 0 {
+		return local, nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for i := 0; i < max(len(lds), len(pds)); i++ {
+		merged := mergeNodes(documentAt(lds, i), documentAt(pds, i), ms)
+		if merged == nil {
+			continue
+		}
+
+		// Local document keeps its head and foot comments.
+		if i < len(lds) && lds[i].Kind == yaml.DocumentNode && len(lds[i].Content) > 0 {
+			lds[i].Content[0] = merged
+			merged = lds[i]
+		}
+
+		if err = enc.Encode(merged); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeDocuments reads every document of YAML stream.
+func decodeDocuments(data []byte) ([]*yaml.Node, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var docs []*yaml.Node
+	for {
+		var n yaml.Node
+		err := dec.Decode(&n)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		docs = append(docs, &n)
+	}
+}
+
+// documentAt returns root node of i-th document or nil if stream is shorter.
+func documentAt(docs []*yaml.Node, i int) *yaml.Node {
+	if i >= len(docs) {
+		return nil
+	}
+
+	return documentRoot(docs[i])
+}
+
+func documentRoot(n *yaml.Node) *yaml.Node {
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		return n.Content[0]
+	}
+
+	if n.Kind == 0 {
+		// Empty document.
+		return nil
+	}
+
+	return n
+}
+
+// mergeNodes merges mappings by keys, lists by strategy list behavior, other values are taken by precedence.
+func mergeNodes(local, pkg *yaml.Node, ms *mergeStrategy) *yaml.Node {
+	if local == nil {
+		return pkg
+	}
+
+	if pkg == nil {
+		return local
+	}
+
+	preferred := local
+	if ms.precedence == MergePrecedencePackage {
+		preferred = pkg
+	}
+
+	switch {
+	case local.Kind == yaml.MappingNode && pkg.Kind == yaml.MappingNode:
+		result := *local
+		result.Content = append([]*yaml.Node{}, local.Content...)
+		for i := 0; i+1 < len(pkg.Content); i += 2 {
+			key, value := pkg.Content[i], pkg.Content[i+1]
+			idx := mappingIndex(&result, key.Value)
+			if idx == -1 {
+				result.Content = append(result.Content, key, value)
+				continue
+			}
+
+			result.Content[idx+1] = mergeNodes(result.Content[idx+1], value, ms)
+		}
+
+		return &result
+	case local.Kind == yaml.SequenceNode && pkg.Kind == yaml.SequenceNode:
+		switch ms.lists {
+		case MergeListsAppend, MergeListsUnique:
+			result := *local
+			result.Content = append([]*yaml.Node{}, local.Content...)
+			for _, item := range pkg.Content {
+				if ms.lists == MergeListsUnique && containsNode(result.Content, item) {
+					continue
+				}
+
+				result.Content = append(result.Content, item)
+			}
+
+			return &result
+		default:
+			return preferred
+		}
+	default:
+		return preferred
+	}
+}
+
+func mappingIndex(n *yaml.Node, key string) int {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func containsNode(items []*yaml.Node, n *yaml.Node) bool {
+	var v any
+	if err := n.Decode(&v); err != nil {
+		return false
+	}
+
+	for _, item := range items {
+		var iv any
+		if err := item.Decode(&iv); err == nil && reflect.DeepEqual(iv, v) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,162 @@
+package compose
+
+import (
+	"testing"
+)
+
+func TestMergeDocuments(t *testing.T) {
+	yamlMerge := func(lists, precedence string) *mergeStrategy {
+		return &mergeStrategy{s: mergeYAMLFiles, lists: lists, precedence: precedence}
+	}
+	jsonMerge := func(lists, precedence string) *mergeStrategy {
+		return &mergeStrategy{s: mergeJSONFiles, lists: lists, precedence: precedence}
+	}
+
+	tests := []struct {
+		name     string
+		local    string
+		pkg      string
+		strategy *mergeStrategy
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "local value wins by default",
+			local:    "a: 1\nb: 2\n",
+			pkg:      "b: 3\nc: 4\n",
+			strategy: yamlMerge("", ""),
+			want:     "a: 1\nb: 2\nc: 4\n",
+		},
+		{
+			name:     "package precedence",
+			local:    "a: 1\nb: 2\n",
+			pkg:      "b: 3\nc: 4\n",
+			strategy: yamlMerge("", MergePrecedencePackage),
+			want:     "a: 1\nb: 3\nc: 4\n",
+		},
+		{
+			name:     "nested mappings are merged by keys",
+			local:    "db:\n  host: local\n  port: 5432\n",
+			pkg:      "db:\n  host: pkg\n  user: admin\n",
+			strategy: yamlMerge("", ""),
+			want:     "db:\n  host: local\n  port: 5432\n  user: admin\n",
+		},
+		{
+			name:     "lists are replaced by preferred document",
+			local:    "l: [a, b]\n",
+			pkg:      "l: [b, c]\n",
+			strategy: yamlMerge(MergeListsReplace, MergePrecedencePackage),
+			want:     "l: [b, c]\n",
+		},
+		{
+			name:     "local list is kept by default",
+			local:    "l: [a, b]\n",
+			pkg:      "l: [b, c]\n",
+			strategy: yamlMerge("", ""),
+			want:     "l: [a, b]\n",
+		},
+		{
+			name:     "lists are appended",
+			local:    "l:\n  - a\n  - b\n",
+			pkg:      "l:\n  - b\n  - c\n",
+			strategy: yamlMerge(MergeListsAppend, ""),
+			want:     "l:\n  - a\n  - b\n  - b\n  - c\n",
+		},
+		{
+			name:     "unique list items are appended",
+			local:    "l:\n  - a\n  - {k: v}\n",
+			pkg:      "l:\n  - {k: v}\n  - c\n",
+			strategy: yamlMerge(MergeListsUnique, ""),
+			want:     "l:\n  - a\n  - {k: v}\n  - c\n",
+		},
+		{
+			name:     "comments and key order of local document are kept",
+			local:    "# head\nz: 1 # z\na: 2\n",
+			pkg:      "a: 3\nm: 4\n",
+			strategy: yamlMerge("", ""),
+			want:     "# head\nz: 1 # z\na: 2\nm: 4\n",
+		},
+		{
+			name:     "multiple documents are merged by position",
+			local:    "a: 1\n---\nb: 1\n",
+			pkg:      "a: 2\nc: 2\n---\nb: 2\nd: 2\n---\ne: 2\n",
+			strategy: yamlMerge("", ""),
+			want:     "a: 1\nc: 2\n---\nb: 1\nd: 2\n---\ne: 2\n",
+		},
+		{
+			name:     "empty local document",
+			local:    "",
+			pkg:      "a: 1\n",
+			strategy: yamlMerge("", ""),
+			want:     "a: 1\n",
+		},
+		{
+			name:     "invalid yaml",
+			local:    "a: [1\n",
+			pkg:      "a: 1\n",
+			strategy: yamlMerge("", ""),
+			wantErr:  true,
+		},
+		{
+			name:     "json",
+			local:    `{"name": "local", "deps": ["a"], "nested": {"x": 1}}`,
+			pkg:      `{"name": "pkg", "deps": ["a", "b"], "nested": {"y": 2}}`,
+			strategy: jsonMerge(MergeListsUnique, MergePrecedencePackage),
+			want:     "{\n  \"name\": \"pkg\",\n  \"deps\": [\n    \"a\",\n    \"b\"\n  ],\n  \"nested\": {\n    \"x\": 1,\n    \"y\": 2\n  }\n}\n",
+		},
+		{
+			name:     "json keeps key order of local document",
+			local:    `{"z": 1, "scripts": {"test": "go test && go vet", "build": "go build"}, "a": 1.50}`,
+			pkg:      `{"scripts": {"lint": "golangci-lint"}, "b": null, "m": true}`,
+			strategy: jsonMerge("", ""),
+			want:     "{\n  \"z\": 1,\n  \"scripts\": {\n    \"test\": \"go test && go vet\",\n    \"build\": \"go build\",\n    \"lint\": \"golangci-lint\"\n  },\n  \"a\": 1.50,\n  \"b\": null,\n  \"m\": true\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeDocuments([]byte(tt.local), []byte(tt.pkg), tt.strategy)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		data    string
+		dedup   bool
+		header  bool
+		want    string
+	}{
+		{name: "concatenated", content: "a\n", data: "b\n", want: "a\nb\n"},
+		{name: "missing newline is added", content: "a", data: "b", want: "a\nb\n"},
+		{name: "header", content: "a\n", data: "b\n", header: true, want: "a\n# pkg\nb\n"},
+		{name: "dedup keeps comments and empty lines", content: "# c\na\n\n", data: "# c\na\n\nb\n", dedup: true, want: "# c\na\n\n# c\n\nb\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := &mergeStrategy{s: appendFiles, dedup: tt.dedup, header: tt.header}
+			got := appendContent([]byte(tt.content), mergeSource{"pkg", []byte(tt.data), ms})
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	errOutsideBuild = errors.New("path is outside of build dir")
+)
+
+// packageMount is a subtree of package composed under target prefix of the build.
+type packageMount struct {
+	subpath string
+	target  string
+}
+
+func getMountsMap(packages []*Package) map[string]packageMount {
+	mounts := make(map[string]packageMount)
+	for _, p := range packages {
+		mounts[p.GetName()] = packageMount{cleanMountPath(p.GetSubpath()), cleanMountPath(p.GetMountTarget())}
+	}
+
+	return mounts
+}
+
+// cleanMountPath returns relative path of package or build, empty path is a root.
+func cleanMountPath(path string) string {
+	path = filepath.Clean(filepath.FromSlash(path))
+	if path == "." {
+		return ""
+	}
+
+	return path
+}
+
+// mountPath returns path of the build for the path of package subtree.
+func mountPath(target, path string) string {
+	if target == "" {
+		return path
+	}
+
+	return filepath.Join(target, path)
+}
+
+// mountParents returns directories of the build containing target, target itself is excluded.
+func mountParents(target string) []string {
+	var parents []string
+	for dir := filepath.Dir(target); target != "" && dir != "."; dir = filepath.Dir(dir) {
+		parents = append([]string{dir}, parents...)
+	}
+
+	return parents
+}
+
+// validateMount checks subpath and target of package.
+func validateMount(pkg *Package) error {
+	if err := ValidateMountPath(pkg.GetSubpath()); err != nil {
+		return fmt.Errorf("invalid subpath of package %s: %w", pkg.GetName(), err)
+	}
+
+	if err := ValidateMountPath(pkg.GetMountTarget()); err != nil {
+		return fmt.Errorf("invalid target of package %s: %w", pkg.GetName(), err)
+	}
+
+	return nil
+}
+
+// ValidateMountPath checks that subpath or target is relative and doesn't leave package or build dir.
+func ValidateMountPath(path string) error {
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "/") {
+		return fmt.Errorf("path %q must be relative", path)
+	}
+
+	clean := filepath.ToSlash(filepath.Clean(path))
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("path %q is outside of directory", path)
+	}
+
+	if clean == gitPrefix || strings.HasPrefix(clean, gitPrefix+"/") {
+		return fmt.Errorf("path %q points to %s directory", path, gitPrefix)
+	}
+
+	return nil
+}
+
+// isInsideDir checks that path doesn't leave dir.
+func isInsideDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
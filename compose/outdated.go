@@ -0,0 +1,241 @@
+package compose
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/launchrctl/launchr"
+)
+
+const (
+	// FormatText is const for human-readable output.
+	FormatText = "text"
+	// FormatJSON is const for JSON output.
+	FormatJSON = "json"
+)
+
+var (
+	errTransitiveNoLock = errors.New("transitive packages are read from plasma-compose.lock, run compose:lock first")
+)
+
+// OutdatedPackage describes how far package lags behind upstream.
+type OutdatedPackage struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	URL        string `json:"url"`
+	Ref        string `json:"ref"`
+	Current    string `json:"current"`
+	Matching   string `json:"matching"`
+	Latest     string `json:"latest"`
+	Transitive bool   `json:"transitive"`
+	Outdated   bool   `json:"outdated"`
+	Error      string `json:"error,omitempty"`
+}
+
+// RunOutdated queries remotes of dependencies and prints current, latest matching and latest overall refs.
+// Transitive dependencies are taken from plasma-compose.lock.
+func (c *Composer) RunOutdated(transitive bool, format string) error {
+	lock, err := LookupLock(os.DirFS(c.pwd))
+	if err != nil {
+		if !errors.Is(err, errLockNotExists) {
+			return err
+		}
+
+		if transitive {
+			return errTransitiveNoLock
+		}
+
+		lock = nil
+	}
+
+	kw := &keyringWrapper{keyringService: c.getKeyring(), shouldUpdate: false, interactive: c.options.Interactive, sshKey: c.options.SSHKey}
+	g := &gitDownloader{k: kw}
+	if format != FormatJSON {
+		launchr.Term().Println("Checking packages remotes...")
+	}
+
+	var result []*OutdatedPackage
+	seen := make(map[string]bool)
+	for _, dep := range c.getCompose().Dependencies {
+		seen[dep.Name] = true
+		result = append(result, checkOutdated(g, dep.ToPackage(dep.Name), lock, false))
+	}
+
+	if transitive {
+		for _, lp := range lock.Packages {
+			if seen[lp.Name] {
+				continue
+			}
+
+			pkg := &Package{Name: lp.Name, Source: Source{Type: lp.Type, URL: lp.URL, Ref: lp.Ref}}
+			result = append(result, checkOutdated(g, pkg, lock, true))
+		}
+	}
+
+	if kw.shouldUpdate {
+		if err = kw.keyringService.Save(); err != nil {
+			return err
+		}
+	}
+
+	if format == FormatJSON {
+		data, errJSON := json.MarshalIndent(result, "", "  ")
+		if errJSON != nil {
+			return errJSON
+		}
+
+		launchr.Term().Println(string(data))
+		return nil
+	}
+
+	printOutdated(result)
+	return nil
+}
+
+// checkOutdated compares locked or declared ref of git package with remote tags and branches.
+func checkOutdated(g *gitDownloader, pkg *Package, lock *YamlLock, transitive bool) *OutdatedPackage {
+	ref := pkg.GetRef()
+	op := &OutdatedPackage{Name: pkg.GetName(), Type: pkg.GetType(), URL: pkg.GetURL(), Ref: ref, Transitive: transitive}
+	if pkg.GetType() != GitType {
+		// Only git remotes provide refs to compare with.
+		return op
+	}
+
+	refs, err := g.listRefs(pkg.GetURL(), git.IgnorePeeled)
+	if err != nil {
+		launchr.Log().Debug("list remote refs error", "package", pkg.GetName(), "err", err)
+		op.Error = err.Error()
+		return op
+	}
+
+	var tags []string
+	heads := make(map[string]string)
+	headTarget := ""
+	for _, r := range refs {
+		switch {
+		case r.Name().IsTag():
+			tags = append(tags, r.Name().Short())
+		case r.Name().IsBranch():
+			heads[r.Name().Short()] = r.Hash().String()
+		case r.Name() == plumbing.HEAD && r.Type() == plumbing.SymbolicReference:
+			headTarget = r.Target().Short()
+		}
+	}
+
+	stable, _ := semver.NewConstraint("*")
+	op.Latest = highestMatchingTag(tags, stable)
+
+	lp := lock.Get(pkg.GetName())
+	if lp != nil && !lp.Matches(pkg) {
+		lp = nil
+	}
+
+	var constraint *semver.Constraints
+	if isVersionConstraint(ref) {
+		constraint, err = semver.NewConstraint(ref)
+		if lp != nil {
+			op.Current = lp.Tag
+		}
+	} else if _, errVer := semver.NewVersion(ref); errVer == nil && slices.Contains(tags, ref) {
+		// Exact tag is compared with compatible tags of the same major version.
+		constraint, err = semver.NewConstraint("^" + ref)
+		op.Current = ref
+	}
+
+	if err != nil {
+		op.Error = err.Error()
+		return op
+	}
+
+	if constraint == nil {
+		branch := ref
+		if branch == "" {
+			branch = headTarget
+		}
+
+		op.Matching = heads[branch]
+		if lp != nil {
+			op.Current = lp.Commit
+		}
+
+		op.Outdated = op.Current != "" && op.Matching != "" && op.Current != op.Matching
+		return op
+	}
+
+	op.Matching = highestMatchingTag(tags, constraint)
+	effective := op.Current
+	if effective == "" {
+		effective = op.Matching
+	}
+
+	op.Outdated = effective != op.Matching || isNewerTag(op.Latest, effective)
+	return op
+}
+
+// isNewerTag checks if tag a is a higher semantic version than tag b.
+func isNewerTag(a, b string) bool {
+	va, errA := semver.NewVersion(a)
+	vb, errB := semver.NewVersion(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+
+	return va.GreaterThan(vb)
+}
+
+// printOutdated prints outdated packages and packages which couldn't be checked as a table.
+func printOutdated(result []*OutdatedPackage) {
+	var rows []*OutdatedPackage
+	for _, op := range result {
+		if op.Outdated || op.Error != "" {
+			rows = append(rows, op)
+		}
+	}
+
+	if len(rows) == 0 {
+		launchr.Term().Success().Println("All packages are up to date")
+		return
+	}
+
+	w := tabwriter.NewWriter(launchr.Term(), 0, 0, 2, ' ', 0)
+	_, _ = w.Write([]byte("PACKAGE\tREF\tCURRENT\tMATCHING\tLATEST\n"))
+	for _, op := range rows {
+		name := op.Name
+		if op.Transitive {
+			name += " (transitive)"
+		}
+
+		cells := []string{name, valueOrDash(op.Ref), valueOrDash(shortRevision(op.Current)), valueOrDash(shortRevision(op.Matching)), valueOrDash(op.Latest)}
+		if op.Error != "" {
+			cells = append(cells[:2], "error: "+op.Error)
+		}
+
+		_, _ = w.Write([]byte(strings.Join(cells, "\t") + "\n"))
+	}
+
+	_ = w.Flush()
+}
+
+// shortRevision shortens commit hash for output, tags are kept as is.
+func shortRevision(rev string) string {
+	if plumbing.IsHash(rev) {
+		return rev[:8]
+	}
+
+	return rev
+}
+
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
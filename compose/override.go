@@ -0,0 +1,127 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/launchrctl/launchr"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	overrideFile = "plasma-compose.override.yaml"
+)
+
+var (
+	errOverrideBadStructure = errors.New("incorrect mapping for plasma-compose.override.yaml, ensure structure is correct")
+	errInvalidOverride      = errors.New("invalid override, expected format is 'name=path'")
+	errFrozenOverride       = errors.New("overrides can't be used in frozen mode")
+)
+
+// YamlOverride stores sources replacing packages sources for local development.
+type YamlOverride struct {
+	Dependencies []Dependency `yaml:"dependencies"`
+}
+
+// LookupOverride reads overrides file if it exists and returns sources by package name.
+func LookupOverride(fsys fs.FS) (map[string]Source, error) {
+	overrides := make(map[string]Source)
+	f, err := fs.ReadFile(fsys, overrideFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return overrides, nil
+		}
+
+		return overrides, err
+	}
+
+	cfg := YamlOverride{}
+	err = yaml.Unmarshal(f, &cfg)
+	if err != nil {
+		return overrides, errOverrideBadStructure
+	}
+
+	for _, dep := range cfg.Dependencies {
+		if dep.Name == "" {
+			return overrides, errOverrideBadStructure
+		}
+
+		overrides[dep.Name] = dep.Source
+	}
+
+	return overrides, nil
+}
+
+// parseOverrides converts overrides in format 'name=path' to local sources.
+func parseOverrides(raw []string) (map[string]Source, error) {
+	overrides := make(map[string]Source)
+	for _, item := range raw {
+		name, path, found := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		path = strings.TrimSpace(path)
+		if !found || name == "" || path == "" {
+			return overrides, fmt.Errorf("%w: %s", errInvalidOverride, item)
+		}
+
+		overrides[name] = Source{Type: LocalType, URL: path}
+	}
+
+	return overrides, nil
+}
+
+// applyOverride replaces package source with overridden one.
+// Package strategies and mount are kept unless override declares its own.
+func applyOverride(pkg *Package, overrides map[string]Source) {
+	src, ok := overrides[pkg.GetName()]
+	if !ok {
+		return
+	}
+
+	if len(src.Strategies) == 0 {
+		src.Strategies = pkg.Source.Strategies
+	}
+
+	if src.Subpath == "" && src.Target == "" {
+		src.Subpath, src.Target = pkg.Source.Subpath, pkg.Source.Target
+	}
+
+	launchr.Log().Debug("package source is overridden", "package", pkg.GetName(), "type", src.Type, "url", src.URL)
+	pkg.Source = src
+}
+
+// printOverridesBanner reminds that packages sources are replaced.
+func printOverridesBanner(overrides map[string]Source) {
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		pkg := &Package{Name: name, Source: overrides[name]}
+		lines = append(lines, fmt.Sprintf("  %s -> %s %s", name, pkg.GetType(), pkg.GetURL()))
+	}
+
+	launchr.Term().Warning().Printfln("Overrides are active, packages sources are replaced:\n%s", strings.Join(lines, "\n"))
+}
+
+// warnUnusedOverrides warns about overrides which don't match any package.
+func warnUnusedOverrides(overrides map[string]Source, packages []*Package) {
+	for name := range overrides {
+		found := false
+		for _, pkg := range packages {
+			if pkg.GetName() == name {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			launchr.Term().Warning().Printfln("Override of %s package doesn't match any package", name)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	errPatchNotApplies = errors.New("patch doesn't apply")
+	errPatchMalformed  = errors.New("malformed patch")
+)
+
+var rgxHunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// packagePatch is a patch file of domain repo applied to files of package.
+type packagePatch struct {
+	pkg  string
+	path string
+}
+
+// filePatch is a list of hunks changing one file.
+type filePatch struct {
+	path  string
+	hunks []*patchHunk
+}
+
+// patchHunk is a change of file, old lines are replaced by new ones.
+type patchHunk struct {
+	header   string
+	line     int
+	oldStart int
+	old      []string
+	new      []string
+}
+
+// defaultPatchPaths returns directory of domain repo with patches of package.
+func defaultPatchPaths(pkgName string) []string {
+	return []string{"patches/" + pkgName}
+}
+
+// retrievePatchPaths returns paths of domain repo with patches of every package which uses patch strategy.
+func retrievePatchPaths(packages []*Package) map[string][]string {
+	pp := make(map[string][]string)
+	for _, pkg := range packages {
+		for _, item := range pkg.GetStrategies() {
+			if s, _ := identifyStrategy(item.Name); s != patchPackageFiles {
+				continue
+			}
+
+			paths := item.Paths
+			if len(paths) == 0 {
+				paths = defaultPatchPaths(pkg.GetName())
+			}
+
+			pp[pkg.GetName()] = append(pp[pkg.GetName()], cleanStrategyPaths(paths)...)
+		}
+	}
+
+	return pp
+}
+
+// isPatchFile checks if file of patches directory is a unified diff.
+func isPatchFile(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".patch" || ext == ".diff"
+}
+
+// applyPatches applies patches of packages in dependencies order to composed package files.
+func applyPatches(entriesMap map[string]*fsEntry, patches []*packagePatch, platformDir string) error {
+	for _, p := range patches {
+		data, err := os.ReadFile(filepath.Join(platformDir, p.path))
+		if err != nil {
+			return err
+		}
+
+		files, err := parsePatch(data)
+		if err != nil {
+			return fmt.Errorf("%w %s: %w", errPatchMalformed, p.path, err)
+		}
+
+		for _, fp := range files {
+			e, ok := entriesMap[fp.path]
+			if !ok || !e.Entry.Mode().IsRegular() {
+				return fmt.Errorf("%w: %s patches build path %s, but it's not a file of the build", errPatchNotApplies, p.path, fp.path)
+			}
+
+			if !isComposedFrom(e, p.pkg) {
+				return fmt.Errorf("%w: %s patches build path %s of package %s, but it's composed from %s", errPatchNotApplies, p.path, fp.path, p.pkg, e.From)
+			}
+
+			content := e.Content
+			if content == nil {
+				content, err = os.ReadFile(e.sourcePath())
+				if err != nil {
+					return err
+				}
+			}
+
+			content, err = applyHunks(content, fp.hunks)
+			if err != nil {
+				return fmt.Errorf("%w: %s to %s of package %s, %w", errPatchNotApplies, p.path, fp.path, p.pkg, err)
+			}
+
+			e.Content = content
+			e.Patches = append(e.Patches, p.path)
+		}
+	}
+
+	return nil
+}
+
+// isComposedFrom checks if package provided entry or was merged into it.
+func isComposedFrom(e *fsEntry, pkg string) bool {
+	if e.From == pkg {
+		return true
+	}
+
+	for _, m := range e.Merges {
+		if m.entry.From == pkg {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parsePatch reads unified diff. Paths are build paths, one 'a/' or 'b/' prefix is stripped.
+func parsePatch(data []byte) ([]*filePatch, error) {
+	lines := splitContentLines(data)
+	var files []*filePatch
+	var cur *filePatch
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			if strings.HasPrefix(line, "--- /dev/null") {
+				return nil, fmt.Errorf("line %d: creating files isn't supported, add file to domain repo", i+1)
+			}
+
+			path, err := patchFilePath(lines[i+1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+2, err)
+			}
+
+			cur = &filePatch{path: path}
+			files = append(files, cur)
+			i++
+		case strings.HasPrefix(line, "@@ "):
+			if cur == nil {
+				return nil, fmt.Errorf("line %d: hunk without file header", i+1)
+			}
+
+			h, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+
+			cur.hunks = append(cur.hunks, h)
+			i = next - 1
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("no file changes found")
+	}
+
+	return files, nil
+}
+
+func patchFilePath(line string) (string, error) {
+	path := strings.TrimSpace(strings.TrimPrefix(line, "+++ "))
+	if i := strings.IndexByte(path, '\t'); i != -1 {
+		// Timestamp follows path.
+		path = path[:i]
+	}
+
+	if path == "/dev/null" {
+		return "", errors.New("removing files isn't supported, use ignore-extra-package-files strategy")
+	}
+
+	if strings.HasPrefix(path, "a/") || strings.HasPrefix(path, "b/") {
+		path = path[2:]
+	}
+
+	return filepath.Clean(path), nil
+}
+
+// parseHunk reads hunk starting at line i, it returns index of the line after hunk.
+func parseHunk(lines []string, i int) (*patchHunk, int, error) {
+	header := strings.TrimRight(lines[i], "\n")
+	m := rgxHunkHeader.FindStringSubmatch(header)
+	if m == nil {
+		return nil, 0, fmt.Errorf("line %d: invalid hunk header %q", i+1, header)
+	}
+
+	oldStart, _ := strconv.Atoi(m[1])
+	oldCount, newCount := 1, 1
+	if m[2] != "" {
+		oldCount, _ = strconv.Atoi(m[2])
+	}
+	if m[4] != "" {
+		newCount, _ = strconv.Atoi(m[4])
+	}
+
+	h := &patchHunk{header: header, line: i + 1, oldStart: oldStart}
+	j := i + 1
+	for ; j < len(lines) && (oldCount > 0 || newCount > 0 || strings.HasPrefix(lines[j], `\`)); j++ {
+		line := lines[j]
+		if line == "\n" {
+			// Editors strip trailing space of empty context line.
+			line = " \n"
+		}
+
+		switch line[0] {
+		case ' ':
+			h.old = append(h.old, line[1:])
+			h.new = append(h.new, line[1:])
+			oldCount--
+			newCount--
+		case '-':
+			h.old = append(h.old, line[1:])
+			oldCount--
+		case '+':
+			h.new = append(h.new, line[1:])
+			newCount--
+		case '\\':
+			// No newline at end of file, previous line is the last one.
+			prev := lines[j-1]
+			if prev[0] == ' ' || prev[0] == '-' {
+				trimLastNewline(h.old)
+			}
+			if prev[0] == ' ' || prev[0] == '+' {
+				trimLastNewline(h.new)
+			}
+		default:
+			return nil, 0, fmt.Errorf("line %d: unexpected line in hunk %q", j+1, header)
+		}
+	}
+
+	if oldCount > 0 || newCount > 0 {
+		return nil, 0, fmt.Errorf("line %d: hunk %q is truncated", i+1, header)
+	}
+
+	return h, j, nil
+}
+
+func trimLastNewline(lines []string) {
+	if len(lines) > 0 {
+		lines[len(lines)-1] = strings.TrimSuffix(lines[len(lines)-1], "\n")
+	}
+}
+
+// applyHunks replaces old lines of every hunk by new ones. Hunk context must match exactly,
+// but it may be found at other line if file was changed above it.
+func applyHunks(content []byte, hunks []*patchHunk) ([]byte, error) {
+	lines := splitContentLines(content)
+	out := make([]string, 0, len(lines))
+	pos, offset := 0, 0
+	for i, h := range hunks {
+		expected := h.oldStart - 1
+		if len(h.old) == 0 {
+			// Pure addition is inserted after the start line.
+			expected = h.oldStart
+		}
+
+		at := findLines(lines, h.old, expected+offset, pos)
+		if at == -1 {
+			return nil, hunkMismatch(lines, h, i+1, expected+offset)
+		}
+
+		out = append(out, lines[pos:at]...)
+		out = append(out, h.new...)
+		pos = at + len(h.old)
+		offset = at - expected
+	}
+
+	out = append(out, lines[pos:]...)
+	return []byte(strings.Join(out, "")), nil
+}
+
+// findLines returns index of the closest to expected occurrence of want at or after min.
+func findLines(lines, want []string, expected, min int) int {
+	for d := 0; expected-d >= min || expected+d <= len(lines)-len(want); d++ {
+		for _, at := range []int{expected + d, expected - d} {
+			if at >= min && at+len(want) <= len(lines) && equalLines(lines[at:at+len(want)], want) {
+				return at
+			}
+		}
+	}
+
+	return -1
+}
+
+func equalLines(a, b []string) bool {
+	for i := range b {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hunkMismatch describes why hunk doesn't apply at expected line.
+func hunkMismatch(lines []string, h *patchHunk, n, expected int) error {
+	if len(h.new) > 0 && findLines(lines, h.new, expected, 0) != -1 {
+		return fmt.Errorf("hunk #%d %s (patch line %d) seems to be already applied, package may include the change now", n, h.header, h.line)
+	}
+
+	for k, want := range h.old {
+		idx := expected + k
+		found := "end of file"
+		if idx >= 0 && idx < len(lines) {
+			if lines[idx] == want {
+				continue
+			}
+
+			found = strconv.Quote(lines[idx])
+		}
+
+		return fmt.Errorf("hunk #%d %s (patch line %d) doesn't match at line %d: expected %q, found %s", n, h.header, h.line, idx+1, want, found)
+	}
+
+	return fmt.Errorf("hunk #%d %s (patch line %d) doesn't match", n, h.header, h.line)
+}
+
+// splitContentLines splits content into lines keeping line endings, missing one of the last line isn't added.
+func splitContentLines(content []byte) []string {
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+
+	return lines
+}
@@ -0,0 +1,229 @@
+package compose
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPatch = `diff --git a/roles/main.yml b/roles/main.yml
+index 1111111..2222222 100644
+--- a/roles/main.yml
++++ b/roles/main.yml
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+@@ -6,3 +6,4 @@
+ f
+ g
+ h
++i
+`
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		patch   string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "applies at declared lines",
+			content: "a\nb\nc\nd\ne\nf\ng\nh\n",
+			patch:   testPatch,
+			want:    "a\nB\nc\nd\ne\nf\ng\nh\ni\n",
+		},
+		{
+			name:    "hunks are found at offset",
+			content: "x\ny\na\nb\nc\nd\ne\nf\ng\nh\n",
+			patch:   testPatch,
+			want:    "x\ny\na\nB\nc\nd\ne\nf\ng\nh\ni\n",
+		},
+		{
+			name:    "hunks are found above declared lines",
+			content: "b\nc\nd\ne\nf\ng\nh\n",
+			patch:   "--- a/f\n+++ b/f\n@@ -5,3 +5,4 @@\n f\n g\n h\n+i\n",
+			want:    "b\nc\nd\ne\nf\ng\nh\ni\n",
+		},
+		{
+			name:    "context mismatch",
+			content: "a\nZ\nc\nd\ne\nf\ng\nh\n",
+			patch:   testPatch,
+			wantErr: `hunk #1 @@ -1,3 +1,3 @@ (patch line 5) doesn't match at line 2: expected "b\n", found "Z\n"`,
+		},
+		{
+			name:    "already applied",
+			content: "a\nB\nc\nd\ne\nf\ng\nh\n",
+			patch:   testPatch,
+			wantErr: "seems to be already applied",
+		},
+		{
+			name:    "file is shorter than hunk",
+			content: "a\nb\n",
+			patch:   testPatch,
+			wantErr: "expected \"c\\n\", found end of file",
+		},
+		{
+			name:    "missing newline at end of file",
+			content: "a\nb",
+			patch:   "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n",
+			want:    "a\nc",
+		},
+		{
+			name:    "pure addition",
+			content: "a\nb\n",
+			patch:   "--- a/f\n+++ b/f\n@@ -1,0 +2,1 @@\n+x\n",
+			want:    "a\nx\nb\n",
+		},
+		{
+			name:    "empty context line without space",
+			content: "a\n\nb\n",
+			patch:   "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n\n-b\n+c\n",
+			want:    "a\n\nc\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := parsePatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+
+			got, err := applyHunks([]byte(tt.content), files[0].hunks)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		paths   []string
+		wantErr string
+	}{
+		{name: "git diff", patch: testPatch, paths: []string{"roles/main.yml"}},
+		{
+			name:  "several files with timestamps",
+			patch: "--- a/one\t2024-01-01\n+++ b/one\t2024-01-01\n@@ -1 +1 @@\n-a\n+b\n--- two\n+++ two\n@@ -1 +1 @@\n-a\n+b\n",
+			paths: []string{"one", "two"},
+		},
+		{name: "single prefix is stripped", patch: "--- a/a/x\n+++ b/a/x\n@@ -1 +1 @@\n-a\n+b\n", paths: []string{"a/x"}},
+		{name: "new file", patch: "--- /dev/null\n+++ b/new\n@@ -0,0 +1 @@\n+a\n", wantErr: "creating files isn't supported"},
+		{name: "deleted file", patch: "--- a/old\n+++ /dev/null\n@@ -1 +0,0 @@\n-a\n", wantErr: "removing files isn't supported"},
+		{name: "no changes", patch: "just text\n", wantErr: "no file changes found"},
+		{name: "hunk without file", patch: "@@ -1 +1 @@\n-a\n+b\n", wantErr: "hunk without file header"},
+		{name: "invalid hunk header", patch: "--- a/f\n+++ b/f\n@@ -x +1 @@\n", wantErr: "invalid hunk header"},
+		{name: "truncated hunk", patch: "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n", wantErr: "is truncated"},
+		{name: "unexpected line", patch: "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n*b\n", wantErr: "unexpected line in hunk"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := parsePatch([]byte(tt.patch))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(files) != len(tt.paths) {
+				t.Fatalf("got %d files, want %d", len(files), len(tt.paths))
+			}
+
+			for i, f := range files {
+				if f.path != filepath.FromSlash(tt.paths[i]) {
+					t.Errorf("file %d path = %s, want %s", i, f.path, tt.paths[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyPatches(t *testing.T) {
+	dir := t.TempDir()
+	pkgDir := filepath.Join(dir, "pkg")
+	if err := os.MkdirAll(filepath.Join(pkgDir, "roles"), 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(pkgDir, "roles", "main.yml"), []byte("a\nb\nc\nd\ne\nf\ng\nh\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "patches", "pkg"), 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "patches", "pkg", "001.patch"), []byte(testPatch), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(filepath.Join(pkgDir, "roles", "main.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		from    string
+		pkg     string
+		target  string
+		wantErr error
+	}{
+		{name: "package file", from: "pkg", pkg: "pkg"},
+		{name: "file of other source", from: domainRepo, pkg: "pkg", wantErr: errPatchNotApplies},
+		{name: "package path of mounted package", from: "pkg", pkg: "pkg", target: "app", wantErr: errPatchNotApplies},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := filepath.Join("roles", "main.yml")
+			path := filepath.Join(tt.target, source)
+			e := &fsEntry{Prefix: pkgDir, Path: path, Source: source, Entry: info, From: tt.from}
+			patches := []*packagePatch{{tt.pkg, filepath.Join("patches", "pkg", "001.patch")}}
+			err := applyPatches(map[string]*fsEntry{path: e}, patches, dir)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(e.Content) != "a\nB\nc\nd\ne\nf\ng\nh\ni\n" || len(e.Patches) != 1 {
+				t.Errorf("unexpected patched entry: %q %v", e.Content, e.Patches)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package compose
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/launchrctl/launchr"
+)
+
+const (
+	// FormatDot is const for Graphviz DOT output.
+	FormatDot = "dot"
+	// FormatMermaid is const for Mermaid flowchart output.
+	FormatMermaid = "mermaid"
+)
+
+// TreeNode is a resolved package of dependency graph.
+type TreeNode struct {
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	URL          string   `json:"url,omitempty"`
+	Path         string   `json:"path,omitempty"`
+	Ref          string   `json:"ref,omitempty"`
+	Revision     string   `json:"revision,omitempty"`
+	Dependencies []string `json:"dependencies"`
+}
+
+// Tree is a resolved dependency graph of plasma-compose.yaml.
+type Tree struct {
+	Name         string      `json:"name"`
+	Dependencies []string    `json:"dependencies"`
+	Packages     []*TreeNode `json:"packages"`
+}
+
+// RunTree resolves dependency graph and prints it in requested format.
+// Without resolve, packages are read from working dir like in offline mode.
+func (c *Composer) RunTree(resolve bool, format string) error {
+	switch format {
+	case FormatText, FormatJSON, FormatDot, FormatMermaid:
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+
+	lock, err := c.getLock()
+	if err != nil {
+		return err
+	}
+
+	// Only the graph must be printed in machine-readable formats.
+	// Credentials can't be requested in muted terminal, missing ones fail the run.
+	if format != FormatText {
+		c.options.Interactive = false
+		launchr.Term().DisableOutput()
+	}
+
+	c.options.Offline = !resolve
+	packages, err := c.resolvePackages(context.Background(), c.getPath(c.options.WorkingDir), lock)
+	launchr.Term().EnableOutput()
+	if err != nil {
+		if !resolve {
+			return fmt.Errorf("%w, run compose or pass --resolve", err)
+		}
+
+		return err
+	}
+
+	tree := createTree(c.getCompose(), packages)
+	switch format {
+	case FormatJSON:
+		data, errJSON := json.MarshalIndent(tree, "", "  ")
+		if errJSON != nil {
+			return errJSON
+		}
+
+		launchr.Term().Println(string(data))
+	case FormatDot:
+		launchr.Term().Print(tree.dot())
+	case FormatMermaid:
+		launchr.Term().Print(tree.mermaid())
+	default:
+		launchr.Term().Print(tree.text())
+	}
+
+	return nil
+}
+
+// createTree collects resolved packages reachable from root plasma-compose.yaml.
+func createTree(root *YamlCompose, packages []*Package) *Tree {
+	tree := &Tree{Name: root.Name, Dependencies: []string{}}
+	if tree.Name == "" {
+		tree.Name = composeFile
+	}
+
+	for _, dep := range root.Dependencies {
+		tree.Dependencies = append(tree.Dependencies, dep.Name)
+	}
+
+	for _, pkg := range packages {
+		node := &TreeNode{
+			Name:         pkg.GetName(),
+			Type:         pkg.GetType(),
+			Revision:     pkg.Revision,
+			Dependencies: []string{},
+		}
+
+		if pkg.GetType() == LocalType {
+			node.Path = pkg.GetURL()
+		} else {
+			node.URL = pkg.GetURL()
+			node.Ref = pkg.GetRef()
+			if pkg.ResolvedRef != "" {
+				node.Ref = fmt.Sprintf("%s (%s)", pkg.GetRef(), pkg.ResolvedRef)
+			}
+		}
+
+		node.Dependencies = append(node.Dependencies, pkg.Dependencies...)
+		tree.Packages = append(tree.Packages, node)
+	}
+
+	// Packages are downloaded in parallel, keep output stable.
+	sort.Slice(tree.Packages, func(i, j int) bool { return tree.Packages[i].Name < tree.Packages[j].Name })
+	return tree
+}
+
+func (t *Tree) get(name string) *TreeNode {
+	for _, n := range t.Packages {
+		if n.Name == name {
+			return n
+		}
+	}
+
+	return nil
+}
+
+// label returns short description of package version.
+func (n *TreeNode) label() string {
+	parts := []string{n.Name}
+	if n.Type == LocalType {
+		parts = append(parts, n.Path)
+	} else if n.Ref != "" {
+		parts = append(parts, n.Ref)
+	}
+
+	if n.Revision != "" {
+		parts = append(parts, shortRevision(n.Revision))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// text prints graph as indented tree, shared packages are printed under every consumer.
+func (t *Tree) text() string {
+	var sb strings.Builder
+	sb.WriteString(t.Name + "\n")
+	t.writeText(&sb, t.Dependencies, "")
+	return sb.String()
+}
+
+func (t *Tree) writeText(sb *strings.Builder, deps []string, indent string) {
+	for i, name := range deps {
+		branch, next := "├── ", "│   "
+		if i == len(deps)-1 {
+			branch, next = "└── ", "    "
+		}
+
+		n := t.get(name)
+		if n == nil {
+			sb.WriteString(indent + branch + name + " (not resolved)\n")
+			continue
+		}
+
+		sb.WriteString(indent + branch + n.label() + "\n")
+		t.writeText(sb, n.Dependencies, indent+next)
+	}
+}
+
+// dot prints graph in Graphviz DOT language.
+func (t *Tree) dot() string {
+	var sb strings.Builder
+	sb.WriteString("digraph compose {\n")
+	sb.WriteString(fmt.Sprintf("  %q [shape=box];\n", t.Name))
+	for _, n := range t.Packages {
+		sb.WriteString(fmt.Sprintf("  %q [label=%q];\n", n.Name, n.label()))
+	}
+
+	for _, name := range t.Dependencies {
+		sb.WriteString(fmt.Sprintf("  %q -> %q;\n", t.Name, name))
+	}
+
+	for _, n := range t.Packages {
+		for _, dep := range n.Dependencies {
+			sb.WriteString(fmt.Sprintf("  %q -> %q;\n", n.Name, dep))
+		}
+	}
+
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// mermaid prints graph as Mermaid flowchart. Node ids are generated, package names may contain any characters.
+func (t *Tree) mermaid() string {
+	ids := make(map[string]string, len(t.Packages))
+	for i, n := range t.Packages {
+		ids[n.Name] = fmt.Sprintf("p%d", i)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("graph TD\n")
+	sb.WriteString(fmt.Sprintf("  root[%q]\n", t.Name))
+	for _, n := range t.Packages {
+		sb.WriteString(fmt.Sprintf("  %s[%q]\n", ids[n.Name], n.label()))
+	}
+
+	for _, name := range t.Dependencies {
+		if id, ok := ids[name]; ok {
+			sb.WriteString(fmt.Sprintf("  root --> %s\n", id))
+		}
+	}
+
+	for _, n := range t.Packages {
+		for _, dep := range n.Dependencies {
+			if id, ok := ids[dep]; ok {
+				sb.WriteString(fmt.Sprintf("  %s --> %s\n", ids[n.Name], id))
+			}
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,128 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/launchrctl/launchr"
+)
+
+// RunWhy explains which source provided path of the build, which other sources provided it
+// and which strategy decided the outcome. Packages are read from working dir like in offline mode.
+func (c *Composer) RunWhy(path string) error {
+	rel, err := c.buildRelPath(path)
+	if err != nil {
+		return err
+	}
+
+	builder, err := c.traceBuild(context.Background())
+	if err != nil {
+		return err
+	}
+
+	origins := builder.origins[rel]
+	if len(origins) == 0 {
+		return fmt.Errorf("%s isn't provided by %s or any package", rel, domainRepo)
+	}
+
+	winner := ""
+	for _, o := range origins {
+		if o.Selected {
+			winner = o.From
+		}
+	}
+
+	if winner == "" {
+		launchr.Term().Warning().Printfln("%s is not composed into %s", rel, BuildDir)
+	} else {
+		launchr.Term().Printfln("%s is composed from %s", rel, describeOrigin(winner, builder.packages))
+	}
+
+	w := tabwriter.NewWriter(launchr.Term(), 0, 0, 2, ' ', 0)
+	_, _ = w.Write([]byte("SOURCE\tSTATUS\tREASON\n"))
+	for _, o := range origins {
+		status := "skipped"
+		reason := o.Reason
+		if o.Selected {
+			status = "selected"
+		} else if o.Merged {
+			status = "merged"
+		} else if reason == "" {
+			reason = "replaced by " + winner
+		}
+
+		_, _ = w.Write([]byte(strings.Join([]string{o.From, status, valueOrDash(reason)}, "\t") + "\n"))
+	}
+
+	return w.Flush()
+}
+
+// traceBuild collects entries of the build from packages in working dir and records origins of every path.
+func (c *Composer) traceBuild(ctx context.Context) (*Builder, error) {
+	lock, err := c.getLock()
+	if err != nil {
+		return nil, err
+	}
+
+	packagesDir := c.getPath(c.options.WorkingDir)
+	c.options.Offline = true
+	c.options.Interactive = false
+	launchr.Term().DisableOutput()
+	packages, err := c.resolvePackages(ctx, packagesDir, lock)
+	launchr.Term().EnableOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%w, run compose first", err)
+	}
+
+	builder := createBuilder(c.pwd, c.getPath(BuildDir), packagesDir, c.options.SkipNotVersioned, false, false, packages)
+	builder.traceOrigins()
+	if _, err = builder.collectEntries(ctx); err != nil {
+		return nil, err
+	}
+
+	return builder, nil
+}
+
+// buildRelPath converts path inside build dir, relative to it or to the working directory into build relative path.
+func (c *Composer) buildRelPath(path string) (string, error) {
+	buildDir := c.getPath(BuildDir)
+	abs := path
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(c.pwd, path)
+		if !strings.HasPrefix(abs, buildDir+string(filepath.Separator)) {
+			abs = filepath.Join(buildDir, path)
+		}
+	}
+
+	rel, err := filepath.Rel(buildDir, abs)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("path %s is outside of %s", path, BuildDir)
+	}
+
+	return filepath.ToSlash(rel), nil
+}
+
+// describeOrigin adds version of package to its name.
+func describeOrigin(from string, packages []*Package) string {
+	for _, pkg := range packages {
+		if pkg.GetName() != from {
+			continue
+		}
+
+		parts := []string{from, pkg.GetTarget()}
+		if pkg.GetType() == LocalType {
+			parts[1] = pkg.GetURL()
+		}
+
+		if pkg.Revision != "" {
+			parts = append(parts, shortRevision(pkg.Revision))
+		}
+
+		return strings.Join(parts, " ")
+	}
+
+	return from
+}
@@ -29,6 +29,14 @@ type Package struct {
 	Name         string   `yaml:"name"`
 	Source       Source   `yaml:"source,omitempty"`
 	Dependencies []string `yaml:"dependencies,omitempty"`
+	// Pin is a revision the package is locked to.
+	Pin string `yaml:"-"`
+	// Revision is a resolved revision of the downloaded package: commit for git and content digest for http.
+	Revision string `yaml:"-"`
+	// Path is a resolved directory of local package.
+	Path string `yaml:"-"`
+	// ResolvedRef is a tag selected by semver range of the ref.
+	ResolvedRef string `yaml:"-"`
 }
 
 // Dependency stores Dependency definition
@@ -39,8 +47,12 @@ type Dependency struct {
 
 // Strategy stores packages merge strategy name and Paths
 type Strategy struct {
-	Name  string   `yaml:"name"`
-	Paths []string `yaml:"path"`
+	Name       string   `yaml:"name"`
+	Paths      []string `yaml:"path"`
+	Lists      string   `yaml:"lists,omitempty"`
+	Precedence string   `yaml:"precedence,omitempty"`
+	Dedup      bool     `yaml:"dedup,omitempty"`
+	Header     bool     `yaml:"header,omitempty"`
 }
 
 // Source stores package source definition
@@ -49,6 +61,11 @@ type Source struct {
 	URL        string     `yaml:"url"`
 	Ref        string     `yaml:"ref,omitempty"`
 	Tag        string     `yaml:"tag,omitempty"`
+	Auth       string     `yaml:"auth,omitempty"`
+	Header     string     `yaml:"header,omitempty"`
+	Checksum   string     `yaml:"checksum,omitempty"`
+	Subpath    string     `yaml:"subpath,omitempty"`
+	Target     string     `yaml:"target,omitempty"`
 	Strategies []Strategy `yaml:"strategy,omitempty"`
 }
 
@@ -70,6 +87,16 @@ func (p *Package) GetStrategies() []Strategy {
 	return p.Source.Strategies
 }
 
+// GetSubpath returns directory of package which is composed, empty for the whole package
+func (p *Package) GetSubpath() string {
+	return p.Source.Subpath
+}
+
+// GetMountTarget returns directory of the build where package is composed, empty for the build root
+func (p *Package) GetMountTarget() string {
+	return p.Source.Target
+}
+
 // GetName from package
 func (p *Package) GetName() string {
 	return p.Name
@@ -100,16 +127,35 @@ func (p *Package) GetRef() string {
 	return ref
 }
 
+// GetResolvedRef returns tag selected by semver range or ref otherwise.
+func (p *Package) GetResolvedRef() string {
+	if p.ResolvedRef != "" {
+		return p.ResolvedRef
+	}
+
+	return p.GetRef()
+}
+
 // GetTag from package source.
 // Deprecated: use [Package.GetRef]
 func (p *Package) GetTag() string {
 	return p.Source.Tag
 }
 
+// GetAuth returns auth mode of http package source.
+func (p *Package) GetAuth() string {
+	a := p.Source.Auth
+	if a == "" {
+		return HTTPAuthBasic
+	}
+
+	return strings.ToLower(a)
+}
+
 // GetTarget returns a target version of package
 func (p *Package) GetTarget() string {
 	target := TargetLatest
-	ref := p.GetRef()
+	ref := p.GetResolvedRef()
 	if ref != "" {
 		target = ref
 	}
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/launchrctl/keyring"
 
@@ -26,6 +28,18 @@ var (
 	actionUpdateYaml []byte
 	//go:embed action.delete.yaml
 	actionDeleteYaml []byte
+	//go:embed action.lock.yaml
+	actionLockYaml []byte
+	//go:embed action.cache.yaml
+	actionCacheYaml []byte
+	//go:embed action.outdated.yaml
+	actionOutdatedYaml []byte
+	//go:embed action.tree.yaml
+	actionTreeYaml []byte
+	//go:embed action.why.yaml
+	actionWhyYaml []byte
+	//go:embed action.diff.yaml
+	actionDiffYaml []byte
 )
 
 func init() {
@@ -66,6 +80,17 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 				SkipNotVersioned:   input.Opt("skip-not-versioned").(bool),
 				ConflictsVerbosity: input.Opt("conflicts-verbosity").(bool),
 				Interactive:        input.Opt("interactive").(bool),
+				UpdateLock:         input.Opt("update-lock").(bool),
+				Frozen:             input.Opt("frozen").(bool),
+				Jobs:               input.Opt("jobs").(int),
+				NoCache:            input.Opt("no-cache").(bool),
+				Offline:            input.Opt("offline").(bool),
+				SSHKey:             input.Opt("ssh-key").(string),
+				Overrides:          action.InputOptSlice[string](input, "override"),
+				ConflictPolicy:     input.Opt("version-conflict").(string),
+				Incremental:        input.Opt("incremental").(bool),
+				DryRun:             input.Opt("dry-run").(bool),
+				Format:             input.Opt("format").(string),
 			},
 			p.k,
 		)
@@ -76,6 +101,135 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 		return c.RunInstall()
 	}))
 
+	// Action compose:lock.
+	lockAction := action.NewFromYAML("compose:lock", actionLockYaml)
+	lockAction.SetRuntime(action.NewFnRuntime(func(_ context.Context, a *action.Action) error {
+		input := a.Input()
+		c, err := compose.CreateComposer(
+			p.wd,
+			compose.ComposerOptions{
+				Clean:          input.Opt("clean").(bool),
+				WorkingDir:     input.Opt("working-dir").(string),
+				Interactive:    input.Opt("interactive").(bool),
+				UpdateLock:     input.Opt("update-lock").(bool),
+				Jobs:           input.Opt("jobs").(int),
+				NoCache:        input.Opt("no-cache").(bool),
+				SSHKey:         input.Opt("ssh-key").(string),
+				ConflictPolicy: input.Opt("version-conflict").(string),
+			},
+			p.k,
+		)
+		if err != nil {
+			return err
+		}
+
+		return c.RunLock()
+	}))
+
+	// Action compose:cache.
+	cacheAction := action.NewFromYAML("compose:cache", actionCacheYaml)
+	cacheAction.SetRuntime(action.NewFnRuntime(func(_ context.Context, a *action.Action) error {
+		input := a.Input()
+		switch input.Arg("command").(string) {
+		case "prune":
+			olderThan, err := time.ParseDuration(input.Opt("older-than").(string))
+			if err != nil {
+				return fmt.Errorf("invalid older-than duration: %w", err)
+			}
+
+			return compose.PruneCache(olderThan)
+		case "size":
+			return compose.ListCache(true)
+		default:
+			return compose.ListCache(false)
+		}
+	}))
+
+	// Action compose:outdated.
+	outdatedAction := action.NewFromYAML("compose:outdated", actionOutdatedYaml)
+	outdatedAction.SetRuntime(action.NewFnRuntime(func(_ context.Context, a *action.Action) error {
+		input := a.Input()
+		c, err := compose.CreateComposer(
+			p.wd,
+			compose.ComposerOptions{
+				Interactive: input.Opt("interactive").(bool),
+				SSHKey:      input.Opt("ssh-key").(string),
+			},
+			p.k,
+		)
+		if err != nil {
+			return err
+		}
+
+		return c.RunOutdated(input.Opt("transitive").(bool), input.Opt("format").(string))
+	}))
+
+	// Action compose:tree.
+	treeAction := action.NewFromYAML("compose:tree", actionTreeYaml)
+	treeAction.SetRuntime(action.NewFnRuntime(func(_ context.Context, a *action.Action) error {
+		input := a.Input()
+		c, err := compose.CreateComposer(
+			p.wd,
+			compose.ComposerOptions{
+				WorkingDir:     input.Opt("working-dir").(string),
+				Interactive:    input.Opt("interactive").(bool),
+				SSHKey:         input.Opt("ssh-key").(string),
+				ConflictPolicy: input.Opt("version-conflict").(string),
+			},
+			p.k,
+		)
+		if err != nil {
+			return err
+		}
+
+		return c.RunTree(input.Opt("resolve").(bool), input.Opt("format").(string))
+	}))
+
+	// Action compose:why.
+	whyAction := action.NewFromYAML("compose:why", actionWhyYaml)
+	whyAction.SetRuntime(action.NewFnRuntime(func(_ context.Context, a *action.Action) error {
+		input := a.Input()
+		c, err := compose.CreateComposer(
+			p.wd,
+			compose.ComposerOptions{
+				WorkingDir:       input.Opt("working-dir").(string),
+				SkipNotVersioned: input.Opt("skip-not-versioned").(bool),
+				Overrides:        action.InputOptSlice[string](input, "override"),
+				ConflictPolicy:   input.Opt("version-conflict").(string),
+			},
+			p.k,
+		)
+		if err != nil {
+			return err
+		}
+
+		return c.RunWhy(input.Arg("path").(string))
+	}))
+
+	// Action compose:diff.
+	diffAction := action.NewFromYAML("compose:diff", actionDiffYaml)
+	diffAction.SetRuntime(action.NewFnRuntime(func(_ context.Context, a *action.Action) error {
+		input := a.Input()
+		c, err := compose.CreateComposer(
+			p.wd,
+			compose.ComposerOptions{
+				WorkingDir:       input.Opt("working-dir").(string),
+				SkipNotVersioned: input.Opt("skip-not-versioned").(bool),
+				Overrides:        action.InputOptSlice[string](input, "override"),
+				ConflictPolicy:   input.Opt("version-conflict").(string),
+			},
+			p.k,
+		)
+		if err != nil {
+			return err
+		}
+
+		return c.RunDiff(compose.DiffOptions{
+			Packages: action.InputOptSlice[string](input, "packages"),
+			Paths:    action.InputOptSlice[string](input, "paths"),
+		})
+	}))
+
 	// Action compose:add.
 	addAction := action.NewFromYAML("compose:add", actionAddYaml)
 	addAction.SetRuntime(action.NewFnRuntime(func(_ context.Context, a *action.Action) error {
@@ -84,15 +238,27 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 			return err
 		}
 		createNew := input.Opt("allow-create").(bool)
+		computeChecksum := input.Opt("compute-checksum").(bool)
+		interactive := input.Opt("interactive").(bool)
 		composeDependency := getInputDependencies(input)
 		strategies := getInputStrategies(input)
-		return compose.AddPackage(createNew, composeDependency, strategies, p.wd)
+		return compose.AddPackage(createNew, computeChecksum, interactive, composeDependency, strategies, p.wd, p.k)
 	}))
 
 	// Action compose:update.
 	updateAction := action.NewFromYAML("compose:update", actionUpdateYaml)
 	updateAction.SetRuntime(action.NewFnRuntime(func(_ context.Context, a *action.Action) error {
 		input := a.Input()
+		if input.Opt("latest").(bool) {
+			return compose.UpdatePackagesLatest(compose.LatestOptions{
+				Packages:    action.InputOptSlice[string](input, "packages"),
+				Within:      input.Opt("within").(string),
+				Constraint:  input.Opt("constraint").(string),
+				Interactive: input.Opt("interactive").(bool),
+				SSHKey:      input.Opt("ssh-key").(string),
+			}, p.wd, p.k)
+		}
+
 		if err := packagePreRunValidate(input); err != nil {
 			return err
 		}
@@ -115,6 +281,12 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 
 	return []*action.Action{
 		composeAction,
+		lockAction,
+		cacheAction,
+		outdatedAction,
+		treeAction,
+		whyAction,
+		diffAction,
 		addAction,
 		updateAction,
 		deleteAction,
@@ -125,10 +297,15 @@ func getInputDependencies(input *action.Input) *compose.Dependency {
 	return &compose.Dependency{
 		Name: input.Opt("package").(string),
 		Source: compose.Source{
-			Type: input.Opt("type").(string),
-			Ref:  input.Opt("ref").(string),
-			Tag:  input.Opt("tag").(string),
-			URL:  input.Opt("url").(string),
+			Type:     input.Opt("type").(string),
+			Ref:      input.Opt("ref").(string),
+			Tag:      input.Opt("tag").(string),
+			URL:      input.Opt("url").(string),
+			Auth:     input.Opt("auth").(string),
+			Header:   input.Opt("header").(string),
+			Checksum: input.Opt("checksum").(string),
+			Subpath:  input.Opt("subpath").(string),
+			Target:   input.Opt("target").(string),
 		},
 	}
 }
@@ -143,12 +320,37 @@ func getInputStrategies(input *action.Input) *compose.RawStrategies {
 func packagePreRunValidate(input *action.Input) error {
 	typeFlag := input.Opt("type").(string)
 
-	if typeFlag == compose.HTTPType {
-		refChanged := input.Opt("ref").(string) != ""
-		if refChanged {
-			launchr.Term().Warning().Println("Ref can't be used with HTTP source")
-			input.SetOpt("ref", "")
+	refChanged := input.Opt("ref").(string) != ""
+	if typeFlag != compose.GitType && refChanged {
+		launchr.Term().Warning().Println("Ref can be used only with Git source")
+		input.SetOpt("ref", "")
+	}
+
+	if typeFlag != compose.HTTPType && (input.Opt("auth").(string) != "" || input.Opt("header").(string) != "" || input.Opt("checksum").(string) != "") {
+		launchr.Term().Warning().Println("Auth, header and checksum can be used only with HTTP source")
+		input.SetOpt("auth", "")
+		input.SetOpt("header", "")
+		input.SetOpt("checksum", "")
+	}
+
+	if err := compose.ValidateChecksum(input.Opt("checksum").(string)); err != nil {
+		return err
+	}
+
+	for _, name := range []string{"subpath", "target"} {
+		if err := compose.ValidateMountPath(input.Opt(name).(string)); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+
+	switch input.Opt("auth").(string) {
+	case "", compose.HTTPAuthBasic, compose.HTTPAuthBearer:
+	case compose.HTTPAuthHeader:
+		if input.Opt("header").(string) == "" {
+			return errors.New("header is required for header auth")
 		}
+	default:
+		return fmt.Errorf("submitted auth %s doesn't exist", input.Opt("auth").(string))
 	}
 
 	strategies := action.InputOptSlice[string](input, "strategy")
@@ -163,6 +365,10 @@ func packagePreRunValidate(input *action.Input) error {
 			compose.StrategyRemoveExtraLocal:   true,
 			compose.StrategyIgnoreExtraPackage: true,
 			compose.StrategyFilterPackage:      true,
+			compose.StrategyMergeYAML:          true,
+			compose.StrategyMergeJSON:          true,
+			compose.StrategyAppendFiles:        true,
+			compose.StrategyPatchPackage:       true,
 		}
 
 		for _, strategy := range strategies {
@@ -170,6 +376,14 @@ func packagePreRunValidate(input *action.Input) error {
 				return fmt.Errorf("submitted strategy %s doesn't exist", strategy)
 			}
 		}
+
+		for _, path := range paths {
+			for _, sp := range strings.Split(path, "|") {
+				if err := compose.ValidateStrategyPath(strings.TrimSpace(sp)); err != nil {
+					return err
+				}
+			}
+		}
 	}
 
 	return nil